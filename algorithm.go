@@ -1,8 +1,12 @@
 package logwriter
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/klauspost/compress/zstd"
 	"io"
 	"sync"
@@ -13,7 +17,73 @@ type Algorithm interface {
 	Decompress(in []byte, out *bytes.Buffer) error
 }
 
-var _ Algorithm = &NopAlgorithm{}
+// FrameAlgorithm is implemented by Algorithm that can read concatenated frames one by one.
+// It is used by NewCompressedReader to decompress large files with bounded memory.
+type FrameAlgorithm interface {
+	Algorithm
+	// ReadFrame reads a frame from r and writes decompressed data to out.
+	// It returns the number of compressed bytes consumed from r.
+	// If r has no more data, it returns io.EOF.
+	// If the frame is truncated, it returns io.ErrUnexpectedEOF.
+	ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error)
+}
+
+// CompressionOption specifies options of the compressor.
+// Zero values mean the default of the algorithm.
+type CompressionOption struct {
+	// Level specifies the compression level. The meaning depends on the algorithm:
+	//	gzip, zlib, deflate: 1 (best speed) to 9 (best compression), or -2 (Huffman only)
+	//	zstd: zstd compatible level 1 (fastest) to 22 (best compression)
+	//	s2: 1 (default), 2 (better compression) or 3 (best compression)
+	Level int
+	// WindowSize specifies the window size in bytes.
+	// It must be a power of two. Only zstd supports this option.
+	WindowSize int
+	// Dictionary specifies the pre-trained dictionary. See TrainDictionary.
+	// It improves the compression ratio of small frames. Only zstd supports this option.
+	Dictionary []byte
+}
+
+// IsZero reports whether all options are default.
+func (o CompressionOption) IsZero() bool {
+	return o.Level == 0 && o.WindowSize == 0 && len(o.Dictionary) == 0
+}
+
+// levelOnly returns an error if options other than Level are specified.
+func (o CompressionOption) levelOnly() error {
+	if o.WindowSize != 0 {
+		return errWindowSizeNotSupported
+	}
+	if len(o.Dictionary) != 0 {
+		return errDictionaryNotSupported
+	}
+	return nil
+}
+
+// ConfigurableAlgorithm is implemented by Algorithm which accepts CompressionOption.
+type ConfigurableAlgorithm interface {
+	Algorithm
+	// Configure validates and applies the options.
+	// It must be called before the first Compress call.
+	Configure(opt CompressionOption) error
+}
+
+var (
+	errWindowSizeNotSupported = errors.New("window size is not supported by this algorithm")
+	errDictionaryNotSupported = errors.New("dictionary is not supported by this algorithm")
+)
+
+// frameSkipper is implemented by FrameAlgorithm that can find frame boundaries without decompression.
+type frameSkipper interface {
+	// SkipFrame skips a frame in r and returns the size of the frame.
+	// size is the size of r.
+	SkipFrame(r io.ReadSeeker, size int64) (int64, error)
+}
+
+// nopFrameSize is the size of frames returned by NopAlgorithm.ReadFrame.
+const nopFrameSize = 64 * (1 << 10) // 64KiB
+
+var _ FrameAlgorithm = &NopAlgorithm{}
 
 type NopAlgorithm struct{}
 
@@ -27,15 +97,62 @@ func (n *NopAlgorithm) Decompress(in []byte, out *bytes.Buffer) error {
 	return err
 }
 
-var _ Algorithm = &GzipAlgorithm{}
+// ReadFrame reads uncompressed data up to 64KiB.
+func (n *NopAlgorithm) ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error) {
+	size, err := io.CopyN(out, r, nopFrameSize)
+	if err == io.EOF && 0 < size {
+		err = nil
+	}
+	return size, err
+}
+
+func (n *NopAlgorithm) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	return nopEncoder{w}, nil
+}
+
+// nopEncoder writes data without compression.
+type nopEncoder struct {
+	io.Writer
+}
+
+func (nopEncoder) Flush() error { return nil }
+func (nopEncoder) Close() error { return nil }
+
+var _ FrameAlgorithm = &GzipAlgorithm{}
+var _ ConfigurableAlgorithm = &GzipAlgorithm{}
+
+// NewGzipAlgorithm returns GzipAlgorithm with the compression level (e.g. gzip.BestSpeed).
+// If level is 0, gzip.DefaultCompression is used.
+func NewGzipAlgorithm(level int) (*GzipAlgorithm, error) {
+	g := &GzipAlgorithm{}
+	if err := g.Configure(CompressionOption{Level: level}); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
 
 type GzipAlgorithm struct {
-	once sync.Once
-	gw   *gzip.Writer
+	once  sync.Once
+	level int
+	gw    *gzip.Writer
+	gr    gzip.Reader
+	err   error
+}
+
+func (g *GzipAlgorithm) Configure(opt CompressionOption) error {
+	if err := opt.levelOnly(); err != nil {
+		return err
+	}
+	g.level = opt.Level
+	g.once.Do(g.init)
+	return g.err
 }
 
 func (g *GzipAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
 	g.once.Do(g.init)
+	if g.err != nil {
+		return g.err
+	}
 	g.gw.Reset(out)
 	_, err := g.gw.Write(in)
 	if err != nil {
@@ -54,16 +171,96 @@ func (g *GzipAlgorithm) Decompress(in []byte, out *bytes.Buffer) error {
 	return err
 }
 
+// ReadFrame reads a gzip member.
+func (g *GzipAlgorithm) ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error) {
+	cr := &countReader{r: r}
+	err := g.gr.Reset(cr)
+	if err == nil {
+		g.gr.Multistream(false)
+		_, err = io.Copy(out, &g.gr)
+	}
+	if err == io.EOF && 0 < cr.n {
+		err = io.ErrUnexpectedEOF
+	}
+	return cr.n, err
+}
+
+func (g *GzipAlgorithm) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	g.once.Do(g.init)
+	if g.err != nil {
+		return nil, g.err
+	}
+	return gzip.NewWriterLevel(w, g.compressionLevel())
+}
+
 func (g *GzipAlgorithm) init() {
-	g.gw = gzip.NewWriter(Discard)
+	g.gw, g.err = gzip.NewWriterLevel(Discard, g.compressionLevel())
 }
 
-var _ Algorithm = &ZstdAlgorithm{}
+func (g *GzipAlgorithm) compressionLevel() int {
+	if g.level == 0 {
+		return gzip.DefaultCompression
+	}
+	return g.level
+}
+
+var _ FrameAlgorithm = &ZstdAlgorithm{}
+var _ ConfigurableAlgorithm = &ZstdAlgorithm{}
+
+// NewZstdAlgorithm returns ZstdAlgorithm with the encoder options,
+// e.g. zstd.WithEncoderLevel(zstd.SpeedBestCompression) and zstd.WithWindowSize(1<<20).
+func NewZstdAlgorithm(opts ...zstd.EOption) (*ZstdAlgorithm, error) {
+	z := &ZstdAlgorithm{opts: opts}
+	z.once.Do(z.init)
+	if z.err != nil {
+		return nil, z.err
+	}
+	return z, nil
+}
 
 type ZstdAlgorithm struct {
-	once sync.Once
-	zw   *zstd.Encoder
-	err  error
+	once  sync.Once
+	opts  []zstd.EOption
+	zw    *zstd.Encoder
+	err   error
+	rOnce sync.Once
+	zr    *zstd.Decoder
+	rErr  error
+	frame []byte
+	// dicts are dictionaries used for decompression.
+	dicts [][]byte
+	// dictDir is the directory to look up dictionary files when the decoder is initialized.
+	dictDir string
+}
+
+func (z *ZstdAlgorithm) Configure(opt CompressionOption) error {
+	if opt.Level != 0 {
+		if opt.Level < 1 || 22 < opt.Level {
+			return fmt.Errorf("invalid zstd level: %d", opt.Level)
+		}
+		z.opts = append(z.opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(opt.Level)))
+	}
+	if opt.WindowSize != 0 {
+		z.opts = append(z.opts, zstd.WithWindowSize(opt.WindowSize))
+	}
+	if len(opt.Dictionary) != 0 {
+		if err := z.AddDictionary(opt.Dictionary); err != nil {
+			return err
+		}
+		z.opts = append(z.opts, zstd.WithEncoderDict(opt.Dictionary))
+	}
+	z.once.Do(z.init)
+	return z.err
+}
+
+// AddDictionary adds the dictionary used to decompress frames.
+// Frames refer to the dictionary by its ID. It must be called before the first Decompress call.
+func (z *ZstdAlgorithm) AddDictionary(dict []byte) error {
+	if _, err := DictionaryID(dict); err != nil {
+		return err
+	}
+	z.dicts = append(z.dicts, dict)
+	return nil
 }
 
 func (z *ZstdAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
@@ -80,15 +277,270 @@ func (z *ZstdAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
 }
 
 func (z *ZstdAlgorithm) Decompress(in []byte, out *bytes.Buffer) error {
-	r, err := zstd.NewReader(bytes.NewReader(in))
+	// The decoder knows the dictionaries. See initReader.
+	z.rOnce.Do(z.initReader)
+	if z.rErr != nil {
+		return z.rErr
+	}
+	decoded, err := z.zr.DecodeAll(in, out.AvailableBuffer())
 	if err != nil {
 		return err
 	}
-	defer r.Close()
-	_, err = io.Copy(out, r)
-	return err
+	out.Write(decoded)
+	return nil
+}
+
+// ReadFrame reads a zstd frame.
+// Skippable frames are consumed without writing anything to out.
+func (z *ZstdAlgorithm) ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error) {
+	z.rOnce.Do(z.initReader)
+	if z.rErr != nil {
+		return 0, z.rErr
+	}
+	var n int64
+	var err error
+	z.frame, n, err = readZstdFrame(r, z.frame[:0])
+	if err != nil {
+		return n, err
+	}
+	if isZstdSkippableFrame(z.frame) {
+		return n, nil
+	}
+	decoded, err := z.zr.DecodeAll(z.frame, out.AvailableBuffer())
+	if err != nil {
+		return n, err
+	}
+	out.Write(decoded)
+	return n, nil
+}
+
+// SkipFrame skips a zstd frame without decompression.
+func (z *ZstdAlgorithm) SkipFrame(r io.ReadSeeker, size int64) (int64, error) {
+	return skipZstdFrame(r, size)
+}
+
+func (z *ZstdAlgorithm) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	z.once.Do(z.init)
+	if z.err != nil {
+		return nil, z.err
+	}
+	return zstd.NewWriter(w, z.encoderOptions()...)
 }
 
 func (z *ZstdAlgorithm) init() {
-	z.zw, z.err = zstd.NewWriter(Discard, zstd.WithEncoderConcurrency(1))
+	z.zw, z.err = zstd.NewWriter(Discard, z.encoderOptions()...)
+}
+
+func (z *ZstdAlgorithm) encoderOptions() []zstd.EOption {
+	return append([]zstd.EOption{zstd.WithEncoderConcurrency(1)}, z.opts...)
+}
+
+func (z *ZstdAlgorithm) initReader() {
+	dicts := z.dicts
+	if z.dictDir != "" {
+		found, err := loadDictionaries(z.dictDir)
+		if err != nil {
+			z.rErr = err
+			return
+		}
+		dicts = append(dicts[:len(dicts):len(dicts)], found...)
+	}
+	z.zr, z.rErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderDicts(dicts...))
+}
+
+const (
+	zstdMagic              = 0xFD2FB528
+	zstdSkippableMagic     = 0x184D2A50
+	zstdSkippableMagicMask = 0xFFFFFFF0
+)
+
+var errInvalidZstdFrame = errors.New("invalid zstd frame")
+
+func isZstdSkippableFrame(frame []byte) bool {
+	return binary.LittleEndian.Uint32(frame)&zstdSkippableMagicMask == zstdSkippableMagic
+}
+
+// readZstdFrame reads a zstd frame from r and appends it to buf without decompression.
+// The payload of a skippable frame is discarded instead of being appended,
+// because its size is read from the header and may be up to 4GiB in a damaged file.
+// It returns the size of the whole frame in r.
+func readZstdFrame(r *bufio.Reader, buf []byte) ([]byte, int64, error) {
+	start := len(buf)
+	var discarded int64
+	read := func(n int) ([]byte, error) {
+		i := len(buf)
+		buf = append(buf, make([]byte, n)...)
+		_, err := io.ReadFull(r, buf[i:])
+		if err == io.EOF && start < i {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf[i:], err
+	}
+	skip := func(n int) error {
+		if !isZstdSkippableFrame(buf[start:]) {
+			_, err := read(n)
+			return err
+		}
+		d, err := r.Discard(n)
+		discarded += int64(d)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	err := scanZstdFrame(read, skip)
+	return buf, int64(len(buf)-start) + discarded, err
+}
+
+// skipZstdFrame skips a zstd frame without reading block contents.
+// size is the size of r. It is used to detect truncated frames.
+// It returns the size of the frame.
+func skipZstdFrame(r io.ReadSeeker, size int64) (int64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	pos := start
+	buf := make([]byte, 16)
+	read := func(n int) ([]byte, error) {
+		_, err := io.ReadFull(r, buf[:n])
+		if err == io.EOF && start < pos {
+			err = io.ErrUnexpectedEOF
+		}
+		pos += int64(n)
+		return buf[:n], err
+	}
+	skip := func(n int) error {
+		pos += int64(n)
+		if size < pos {
+			return io.ErrUnexpectedEOF
+		}
+		_, err := r.Seek(pos, io.SeekStart)
+		return err
+	}
+	err = scanZstdFrame(read, skip)
+	return pos - start, err
+}
+
+// scanZstdFrame parses a zstd frame.
+// read reads n bytes of headers, and skip consumes n bytes of contents.
+// See RFC 8878 for the frame format.
+func scanZstdFrame(read func(n int) ([]byte, error), skip func(n int) error) error {
+	b, err := read(4)
+	if err != nil {
+		return err
+	}
+	magic := binary.LittleEndian.Uint32(b)
+	if magic&zstdSkippableMagicMask == zstdSkippableMagic {
+		if b, err = read(4); err != nil {
+			return err
+		}
+		return skip(int(binary.LittleEndian.Uint32(b)))
+	}
+	if magic != zstdMagic {
+		return fmt.Errorf("%w: unknown magic number %#x", errInvalidZstdFrame, magic)
+	}
+
+	// Frame header.
+	if b, err = read(1); err != nil {
+		return err
+	}
+	descriptor := b[0]
+	fcsFlag := descriptor >> 6
+	singleSegment := descriptor&(1<<5) != 0
+	hasChecksum := descriptor&(1<<2) != 0
+	headerSize := []int{0, 1, 2, 4}[descriptor&3]
+	if !singleSegment {
+		// Window descriptor.
+		headerSize++
+	}
+	switch fcsFlag {
+	case 0:
+		if singleSegment {
+			headerSize++
+		}
+	default:
+		headerSize += 1 << fcsFlag
+	}
+	if _, err = read(headerSize); err != nil {
+		return err
+	}
+
+	// Blocks.
+	for last := false; !last; {
+		if b, err = read(3); err != nil {
+			return err
+		}
+		h := uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+		last = h&1 != 0
+		size := int(h >> 3)
+		switch (h >> 1) & 3 {
+		case 0, 2:
+			// Raw block and compressed block.
+		case 1:
+			// RLE block.
+			size = 1
+		default:
+			return fmt.Errorf("%w: reserved block type", errInvalidZstdFrame)
+		}
+		if err = skip(size); err != nil {
+			return err
+		}
+	}
+
+	if hasChecksum {
+		return skip(4)
+	}
+	return nil
+}
+
+// streamWriter is a compressor which can be reused by Reset.
+type streamWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compressStream compresses in as a stream, and writes it to out.
+func compressStream(w streamWriter, in []byte, out *bytes.Buffer) error {
+	w.Reset(out)
+	_, err := w.Write(in)
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// decompressFrames decompresses all frames in in.
+func decompressFrames(a FrameAlgorithm, in []byte, out *bytes.Buffer) error {
+	r := bufio.NewReader(bytes.NewReader(in))
+	for {
+		_, err := a.ReadFrame(r, out)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// countReader counts the number of bytes read from r.
+// It implements io.ByteReader to prevent decompressors from reading ahead.
+type countReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
 }
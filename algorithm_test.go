@@ -44,3 +44,71 @@ func FuzzZstdAlgorithm_Compress(f *testing.F) {
 	a := &ZstdAlgorithm{}
 	fuzzAlgorithm(f, a)
 }
+
+func FuzzS2Algorithm_Compress(f *testing.F) {
+	a := &S2Algorithm{}
+	fuzzAlgorithm(f, a)
+}
+
+func FuzzSnappyAlgorithm_Compress(f *testing.F) {
+	a := &SnappyAlgorithm{}
+	fuzzAlgorithm(f, a)
+}
+
+func FuzzDeflateAlgorithm_Compress(f *testing.F) {
+	a := &DeflateAlgorithm{}
+	fuzzAlgorithm(f, a)
+}
+
+func FuzzZlibAlgorithm_Compress(f *testing.F) {
+	a := &ZlibAlgorithm{}
+	fuzzAlgorithm(f, a)
+}
+
+func TestConfigurableAlgorithm_Configure(t *testing.T) {
+	plain := bytes.Repeat([]byte("0123456789\n"), 1000)
+	for _, c := range []struct {
+		new func() ConfigurableAlgorithm
+		opt CompressionOption
+	}{
+		{func() ConfigurableAlgorithm { return &GzipAlgorithm{} }, CompressionOption{Level: 1}},
+		{func() ConfigurableAlgorithm { return &GzipAlgorithm{} }, CompressionOption{Level: 9}},
+		{func() ConfigurableAlgorithm { return &GzipAlgorithm{} }, CompressionOption{Level: -2}},
+		{func() ConfigurableAlgorithm { return &ZstdAlgorithm{} }, CompressionOption{Level: 1}},
+		{func() ConfigurableAlgorithm { return &ZstdAlgorithm{} }, CompressionOption{Level: 19, WindowSize: 1 << 20}},
+		{func() ConfigurableAlgorithm { return &S2Algorithm{} }, CompressionOption{Level: 2}},
+		{func() ConfigurableAlgorithm { return &S2Algorithm{} }, CompressionOption{Level: 3}},
+		{func() ConfigurableAlgorithm { return &DeflateAlgorithm{} }, CompressionOption{Level: 1}},
+		{func() ConfigurableAlgorithm { return &ZlibAlgorithm{} }, CompressionOption{Level: 9}},
+	} {
+		a := c.new()
+		if assert.NoError(t, a.Configure(c.opt), "%T %+v", a, c.opt) {
+			testAlgorithm(t, a, plain)
+		}
+	}
+
+	for _, c := range []struct {
+		new func() ConfigurableAlgorithm
+		opt CompressionOption
+	}{
+		{func() ConfigurableAlgorithm { return &GzipAlgorithm{} }, CompressionOption{Level: 10}},
+		{func() ConfigurableAlgorithm { return &GzipAlgorithm{} }, CompressionOption{WindowSize: 1 << 15}},
+		{func() ConfigurableAlgorithm { return &ZstdAlgorithm{} }, CompressionOption{Level: 23}},
+		{func() ConfigurableAlgorithm { return &ZstdAlgorithm{} }, CompressionOption{WindowSize: 1000}},
+		{func() ConfigurableAlgorithm { return &S2Algorithm{} }, CompressionOption{Level: 4}},
+		{func() ConfigurableAlgorithm { return &DeflateAlgorithm{} }, CompressionOption{Level: 10}},
+		{func() ConfigurableAlgorithm { return &ZlibAlgorithm{} }, CompressionOption{Level: -3}},
+	} {
+		a := c.new()
+		assert.Error(t, a.Configure(c.opt), "%T %+v", a, c.opt)
+	}
+}
+
+func TestNewGzipAlgorithm(t *testing.T) {
+	_, err := NewGzipAlgorithm(10)
+	assert.Error(t, err)
+	a, err := NewGzipAlgorithm(1)
+	if assert.NoError(t, err) {
+		testAlgorithm(t, a, []byte("aaaa"))
+	}
+}
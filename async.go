@@ -0,0 +1,183 @@
+package logwriter
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy specifies the behavior of AsyncWriter when the queue is full.
+type OverflowPolicy int
+
+const (
+	// Block waits until the queue has enough space.
+	Block OverflowPolicy = iota
+	// DropNewest discards the data being written.
+	DropNewest
+	// DropOldest discards the oldest data in the queue until the data being written fits in the queue.
+	DropOldest
+)
+
+// NewAsyncWriter wraps the provided io.WriteCloser with an AsyncWriter.
+// size is the capacity of the queue in bytes.
+func NewAsyncWriter(w io.WriteCloser, size int, policy OverflowPolicy) *AsyncWriter {
+	a := &AsyncWriter{
+		w:      w,
+		size:   size,
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mux)
+	go a.worker()
+	return a
+}
+
+// AsyncWriter copies written data to a bounded queue, and writes it to w in the background.
+// Write does not wait for slow operations in w such as compression and disk I/O.
+// Each Write call is passed to w as is, in the same order.
+//
+// A record larger than the queue is accepted if the queue is empty.
+type AsyncWriter struct {
+	w      io.WriteCloser
+	size   int
+	policy OverflowPolicy
+	mux    sync.Mutex
+	// cond is signaled when a record is added to or removed from the queue, or the writer is closed.
+	cond   *sync.Cond
+	queue  [][]byte
+	queued int
+	// writing is true while the worker writes a record popped from the queue.
+	writing bool
+	// err is the first error returned by w.
+	err    error
+	closed bool
+	done   chan struct{}
+
+	droppedRecords atomic.Uint64
+	droppedBytes   atomic.Uint64
+}
+
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if a.closed {
+		return 0, os.ErrClosed
+	}
+	if a.err != nil {
+		return 0, a.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for a.size < a.queued+len(p) && 0 < len(a.queue) {
+		switch a.policy {
+		case DropNewest:
+			a.drop(p)
+			return len(p), nil
+		case DropOldest:
+			a.drop(a.queue[0])
+			a.pop()
+		default:
+			a.cond.Wait()
+			if a.closed {
+				return 0, os.ErrClosed
+			}
+		}
+	}
+	a.queue = append(a.queue, bytes.Clone(p))
+	a.queued += len(p)
+	a.cond.Broadcast()
+	return len(p), nil
+}
+
+// Close waits until all queued data is written, and closes w.
+func (a *AsyncWriter) Close() error {
+	a.mux.Lock()
+	if a.closed {
+		a.mux.Unlock()
+		return os.ErrClosed
+	}
+	a.closed = true
+	a.cond.Broadcast()
+	a.mux.Unlock()
+
+	<-a.done
+	return a.err
+}
+
+// Reopen waits until all queued data is written, and calls Reopen of w if w implements Reopener.
+// Write blocks while reopening.
+func (a *AsyncWriter) Reopen() error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	for (0 < len(a.queue) || a.writing) && !a.closed {
+		a.cond.Wait()
+	}
+	if a.closed {
+		return os.ErrClosed
+	}
+	if a.err != nil {
+		return a.err
+	}
+	a.err = reopen(a.w)
+	return a.err
+}
+
+// Dropped returns the number of records and bytes discarded due to the overflow of the queue.
+func (a *AsyncWriter) Dropped() (records, bytes uint64) {
+	return a.droppedRecords.Load(), a.droppedBytes.Load()
+}
+
+func (a *AsyncWriter) drop(p []byte) {
+	a.droppedRecords.Add(1)
+	a.droppedBytes.Add(uint64(len(p)))
+}
+
+// pop removes the oldest record from the queue.
+func (a *AsyncWriter) pop() []byte {
+	p := a.queue[0]
+	a.queue[0] = nil
+	a.queue = a.queue[1:]
+	a.queued -= len(p)
+	a.cond.Broadcast()
+	return p
+}
+
+func (a *AsyncWriter) worker() {
+	defer close(a.done)
+	for {
+		a.mux.Lock()
+		for len(a.queue) == 0 && !a.closed {
+			a.cond.Wait()
+		}
+		if len(a.queue) == 0 {
+			// Closed and all data was written.
+			a.mux.Unlock()
+			break
+		}
+		p := a.pop()
+		a.writing = true
+		a.mux.Unlock()
+
+		_, err := a.w.Write(p)
+		a.mux.Lock()
+		a.writing = false
+		if a.err == nil {
+			a.err = err
+		}
+		a.cond.Broadcast()
+		a.mux.Unlock()
+	}
+	a.setErr(a.w.Close())
+}
+
+func (a *AsyncWriter) setErr(err error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if a.err == nil {
+		a.err = err
+	}
+}
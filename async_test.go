@@ -0,0 +1,143 @@
+package logwriter
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+// gateWriter blocks Write until the gate is opened.
+type gateWriter struct {
+	bufferTestWriter
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{
+		started: make(chan struct{}, 100),
+		gate:    make(chan struct{}),
+	}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	g.started <- struct{}{}
+	<-g.gate
+	return g.bufferTestWriter.Write(p)
+}
+
+func TestAsyncWriter_Write(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  OverflowPolicy
+		actions []interface{}
+		dropped uint64
+	}{
+		{
+			name:   "drop newest",
+			policy: DropNewest,
+			actions: []interface{}{
+				&bufferWriteAction{Data: "1111"},
+				&bufferWriteAction{Data: "2222"},
+				&bufferWriteAction{Data: "3333"},
+				&bufferCloseAction{},
+			},
+			dropped: 1,
+		}, {
+			name:   "drop oldest",
+			policy: DropOldest,
+			actions: []interface{}{
+				&bufferWriteAction{Data: "1111"},
+				&bufferWriteAction{Data: "3333"},
+				&bufferWriteAction{Data: "4444"},
+				&bufferCloseAction{},
+			},
+			dropped: 1,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			gw := newGateWriter()
+			aw := NewAsyncWriter(gw, 10, testCase.policy)
+			write := func(data string) {
+				n, err := aw.Write([]byte(data))
+				assert.NoError(t, err)
+				assert.Equal(t, len(data), n)
+			}
+			write("1111")
+			// Wait until the worker starts writing the first record.
+			<-gw.started
+			write("2222")
+			write("3333")
+			// Overflow.
+			write("4444")
+
+			records, bytes := aw.Dropped()
+			assert.Equal(t, testCase.dropped, records)
+			assert.Equal(t, 4*testCase.dropped, bytes)
+
+			close(gw.gate)
+			assert.NoError(t, aw.Close())
+			assert.Equal(t, testCase.actions, gw.Actions)
+			_, err := aw.Write([]byte("closed"))
+			assert.Equal(t, os.ErrClosed, err)
+		})
+	}
+}
+
+func TestAsyncWriter_Write_block(t *testing.T) {
+	gw := newGateWriter()
+	aw := NewAsyncWriter(gw, 10, Block)
+	_, err := aw.Write([]byte("1111"))
+	assert.NoError(t, err)
+	<-gw.started
+	_, err = aw.Write([]byte("2222"))
+	assert.NoError(t, err)
+	_, err = aw.Write([]byte("3333"))
+	assert.NoError(t, err)
+
+	wrote := make(chan struct{})
+	go func() {
+		defer close(wrote)
+		_, err := aw.Write([]byte("4444"))
+		assert.NoError(t, err)
+	}()
+	select {
+	case <-wrote:
+		assert.FailNow(t, "Write() should be blocked")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(gw.gate)
+	<-wrote
+	assert.NoError(t, aw.Close())
+
+	records, _ := aw.Dropped()
+	assert.Equal(t, uint64(0), records)
+	assert.Equal(t, []interface{}{
+		&bufferWriteAction{Data: "1111"},
+		&bufferWriteAction{Data: "2222"},
+		&bufferWriteAction{Data: "3333"},
+		&bufferWriteAction{Data: "4444"},
+		&bufferCloseAction{},
+	}, gw.Actions)
+}
+
+type errorWriter struct {
+	bufferTestWriter
+	err error
+}
+
+func (e *errorWriter) Write(p []byte) (int, error) {
+	return 0, e.err
+}
+
+func TestAsyncWriter_Close_error(t *testing.T) {
+	ew := &errorWriter{err: errors.New("disk full")}
+	aw := NewAsyncWriter(ew, 10, Block)
+	_, err := aw.Write([]byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, ew.err, aw.Close())
+	assert.Equal(t, []interface{}{&bufferCloseAction{}}, ew.Actions)
+}
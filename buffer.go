@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -11,7 +12,7 @@ func NewBuffer(size int, interval time.Duration, w io.WriteCloser) io.WriteClose
 	return newBuffer(size, interval, w, time.Now)
 }
 
-func newBuffer(size int, interval time.Duration, w io.WriteCloser, now func() time.Time) io.WriteCloser {
+func newBuffer(size int, interval time.Duration, w io.WriteCloser, now func() time.Time) *Buffer {
 	return &Buffer{
 		Size:      size,
 		Interval:  interval,
@@ -27,12 +28,35 @@ type Buffer struct {
 	Interval time.Duration
 	// Now() is a function returns current time like time.Time().
 	// This function used to inject time from outside.
-	Now       func() time.Time
-	w         io.WriteCloser
-	buf       bytes.Buffer
-	err       error
-	lastFlush time.Time
-	closed    bool
+	Now func() time.Time
+	// MaxInFlight specifies the number of buffers being flushed in the background.
+	// If MaxInFlight is positive, the full buffer is handed to a background goroutine and
+	// new writes continue into a fresh buffer.
+	// Errors occurred in the background are returned by subsequent Write and Close calls.
+	// If MaxInFlight is not a positive value, the buffer is flushed in the caller's goroutine.
+	MaxInFlight int
+	// Delimiter enables record-aware mode.
+	// In record-aware mode, the buffer is flushed only at the end of a record terminated by Delimiter (e.g. "\n"),
+	// so data passed to w always consists of whole records.
+	// A partial record at the end of the buffer is held until it completes, MaxHold passes, it exceeds Size,
+	// or the buffer is closed.
+	// If Delimiter is empty, the buffer is flushed at any byte.
+	Delimiter []byte
+	// MaxHold specifies how long a partial record is held in record-aware mode.
+	// If MaxHold is not a positive value, a partial record is held without time limit.
+	MaxHold time.Duration
+	// holdSince is the time when the partial record started being held.
+	holdSince time.Time
+	// firstWrite and lastWrite are the time range of writes in the buffer.
+	// They are passed to writers recording the time range of frames (e.g. time index).
+	firstWrite time.Time
+	lastWrite  time.Time
+	flusher    *flusher
+	w          io.WriteCloser
+	buf        bytes.Buffer
+	err        error
+	lastFlush  time.Time
+	closed     bool
 }
 
 func (b *Buffer) Write(p []byte) (n int, err error) {
@@ -45,23 +69,85 @@ func (b *Buffer) Close() (err error) {
 	return
 }
 
-func (b *Buffer) flush() {
+// flush writes the buffered data to w.
+// If force is false, a partial record is kept in the buffer in record-aware mode.
+func (b *Buffer) flush(force bool) {
 	if b.err != nil {
 		return
 	}
-	n := b.buf.Len()
+	n := b.cut(force)
+	now := b.Now()
+	switch {
+	case n == b.buf.Len():
+		b.holdSince = time.Time{}
+	case 0 < n || b.holdSince.IsZero():
+		// A new partial record is held.
+		b.holdSince = now
+	}
+	b.lastFlush = now
+	first, last := b.firstWrite, b.lastWrite
+	if n == b.buf.Len() {
+		b.firstWrite = time.Time{}
+	}
+
+	if 0 < b.MaxInFlight {
+		b.flushBackground(n, first, last)
+		return
+	}
 	var wrote int
 	if n > 0 {
-		wrote, b.err = b.w.Write(b.buf.Bytes())
+		wrote, b.err = writeTimed(b.w, b.buf.Bytes()[:n], first, last)
 	}
-	b.lastFlush = b.Now()
-	b.buf.Reset()
+	b.buf.Next(n)
 	if b.err == nil && wrote < n {
 		b.err = io.ErrShortWrite
 	}
 	return
 }
 
+// cut returns the length of data to be flushed.
+func (b *Buffer) cut(force bool) int {
+	data := b.buf.Bytes()
+	if force || len(b.Delimiter) == 0 {
+		return len(data)
+	}
+	if i := bytes.LastIndex(data, b.Delimiter); 0 <= i {
+		return i + len(b.Delimiter)
+	}
+	// The buffer has only a partial record.
+	expired := 0 < b.MaxHold && !b.holdSince.IsZero() && b.MaxHold <= b.Now().Sub(b.holdSince)
+	if expired || b.Size <= len(data) {
+		return len(data)
+	}
+	return 0
+}
+
+// flushBackground hands the first n bytes of the buffer to the background goroutine.
+func (b *Buffer) flushBackground(n int, first, last time.Time) {
+	if n == 0 {
+		return
+	}
+	if b.flusher == nil {
+		b.flusher = newFlusher(b.w, b.MaxInFlight)
+	}
+	data := b.buf.Bytes()
+	p, rest := data[:n], data[n:]
+	b.buf = *bytes.NewBuffer(append(b.flusher.buffer(), rest...))
+	b.flusher.submit(timedData{p: p, first: first, last: last})
+	b.err = b.flusher.Err()
+}
+
+// waitBackground waits for all buffers in the background to be flushed.
+func (b *Buffer) waitBackground() {
+	if b.flusher == nil {
+		return
+	}
+	b.flusher.wait()
+	if b.err == nil {
+		b.err = b.flusher.Err()
+	}
+}
+
 func (b *Buffer) needFlush() bool {
 	expired := b.Now().Sub(b.lastFlush).Abs().Nanoseconds() >= b.Interval.Nanoseconds()
 	overflow := b.Size <= b.buf.Len()
@@ -72,33 +158,45 @@ func (b *Buffer) write(p []byte) (int, error) {
 	if b.closed {
 		return 0, os.ErrClosed
 	}
-	if b.Size <= len(p) {
+	if b.err == nil && b.flusher != nil {
+		b.err = b.flusher.Err()
+	}
+	if b.Size <= len(p) && len(b.Delimiter) == 0 {
 		return b.largeWrite(p)
 	}
+	// In record-aware mode, large data is also buffered to find the end of the last record.
 	return b.smallWrite(p)
 }
 
 func (b *Buffer) smallWrite(p []byte) (int, error) {
 	var n int
 	if b.err == nil && 0 < len(p) {
+		now := b.Now()
+		if b.firstWrite.IsZero() {
+			b.firstWrite = now
+		}
+		b.lastWrite = now
 		// This operation may require a buffer space of twice b.Size.
 		n, b.err = b.buf.Write(p)
 	}
 	if b.err == nil && b.needFlush() {
-		b.flush()
+		b.flush(false)
 	}
 	return n, b.err
 }
 
 func (b *Buffer) largeWrite(p []byte) (int, error) {
-	b.flush()
+	b.flush(true)
+	// Keep order of data. p is written in the caller's goroutine after the background flushes.
+	b.waitBackground()
 	if b.err == nil && b.buf.Len() != 0 {
 		panic("bug: internal buffer is not empty even though it was flushed")
 	}
 	var wrote int
 	if b.err == nil {
-		wrote, b.err = b.w.Write(p)
-		b.lastFlush = b.Now()
+		now := b.Now()
+		wrote, b.err = writeTimed(b.w, p, now, now)
+		b.lastFlush = now
 	}
 	if b.err == nil && wrote < len(p) {
 		b.err = io.ErrShortWrite
@@ -106,14 +204,136 @@ func (b *Buffer) largeWrite(p []byte) (int, error) {
 	return wrote, b.err
 }
 
+// Reopen flushes the buffer, and calls Reopen of w if w implements Reopener.
+func (b *Buffer) Reopen() error {
+	if b.closed {
+		return os.ErrClosed
+	}
+	b.flush(true)
+	b.waitBackground()
+	if b.err == nil {
+		b.err = reopen(b.w)
+	}
+	return b.err
+}
+
+// checkFile calls checkFile of w after the background flushes.
+func (b *Buffer) checkFile() error {
+	if b.closed {
+		return os.ErrClosed
+	}
+	b.waitBackground()
+	if b.err == nil {
+		b.err = checkFile(b.w)
+	}
+	return b.err
+}
+
 func (b *Buffer) close() error {
 	if b.closed {
 		return os.ErrClosed
 	}
-	b.flush()
+	b.flush(true)
+	b.waitBackground()
+	if b.flusher != nil {
+		b.flusher.close()
+		b.flusher = nil
+	}
 	if b.err == nil {
 		b.err = b.w.Close()
 		b.closed = true
 	}
 	return b.err
 }
+
+func newFlusher(w io.Writer, inFlight int) *flusher {
+	f := &flusher{
+		w:     w,
+		ch:    make(chan timedData, inFlight),
+		slots: make(chan struct{}, inFlight),
+		free:  make(chan []byte, inFlight),
+		done:  make(chan struct{}),
+	}
+	go f.worker()
+	return f
+}
+
+// timedData is data with the time range of writes.
+type timedData struct {
+	p           []byte
+	first, last time.Time
+}
+
+// flusher writes buffers to w in the background in the order they are submitted.
+// Methods except Err must be called from a single goroutine.
+type flusher struct {
+	w io.Writer
+	// ch sends buffers to the worker.
+	ch chan timedData
+	// slots limits the number of buffers in flight.
+	slots chan struct{}
+	// free holds buffers already written to be reused.
+	free chan []byte
+	done chan struct{}
+	mux  sync.Mutex
+	err  error
+}
+
+// buffer returns an empty buffer to be reused.
+func (f *flusher) buffer() []byte {
+	select {
+	case p := <-f.free:
+		return p
+	default:
+		return nil
+	}
+}
+
+// submit hands d to the worker. It blocks while the maximum number of buffers are in flight.
+func (f *flusher) submit(d timedData) {
+	f.slots <- struct{}{}
+	f.ch <- d
+}
+
+// wait waits until all submitted buffers are written.
+func (f *flusher) wait() {
+	for i := 0; i < cap(f.slots); i++ {
+		f.slots <- struct{}{}
+	}
+	for i := 0; i < cap(f.slots); i++ {
+		<-f.slots
+	}
+}
+
+func (f *flusher) close() {
+	close(f.ch)
+	<-f.done
+}
+
+// Err returns the first error occurred in the background.
+func (f *flusher) Err() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.err
+}
+
+func (f *flusher) worker() {
+	defer close(f.done)
+	for d := range f.ch {
+		p := d.p
+		if f.Err() == nil {
+			n, err := writeTimed(f.w, p, d.first, d.last)
+			if err == nil && n < len(p) {
+				err = io.ErrShortWrite
+			}
+			f.mux.Lock()
+			f.err = err
+			f.mux.Unlock()
+		}
+		select {
+		case f.free <- p[:0]:
+		default:
+		}
+		<-f.slots
+	}
+}
@@ -1,6 +1,8 @@
 package logwriter
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 	"time"
@@ -38,9 +40,11 @@ func TestBuffer_Write(t *testing.T) {
 	}
 
 	cases := []struct {
-		name     string
-		size     int
-		interval time.Duration
+		name      string
+		size      int
+		interval  time.Duration
+		delimiter string
+		maxHold   time.Duration
 		// List of WriteOperation and CloseOperation and UpdateDurationOperation().
 		ops []interface{}
 		// List of bufferWriteAction and bufferCloseAction.
@@ -114,35 +118,107 @@ func TestBuffer_Write(t *testing.T) {
 				&bufferWriteAction{Data: "next message 3\n"},
 				&bufferCloseAction{},
 			},
+		}, {
+			name:      "record-aware: cut at the end of the last line",
+			size:      10,
+			delimiter: "\n",
+			ops: []interface{}{
+				&WriteOperation{data: "foo\nba"}, // 6 byte.
+				&WriteOperation{data: "r\nbaz"},  // 11 byte, flush buffer except "baz".
+				&WriteOperation{data: "\nqux\n"}, // 8 byte.
+				&CloseOperation{},
+			},
+			actions: []interface{}{
+				&bufferWriteAction{Data: "foo\nbar\n"},
+				&bufferWriteAction{Data: "baz\nqux\n"},
+				&bufferCloseAction{},
+			},
+		}, {
+			name:      "record-aware: large write",
+			size:      5,
+			delimiter: "\n",
+			ops: []interface{}{
+				&WriteOperation{data: "ab\ncdefg"},
+				&WriteOperation{data: "hij"},
+				&CloseOperation{},
+			},
+			actions: []interface{}{
+				&bufferWriteAction{Data: "ab\n"},
+				// The partial line exceeds the buffer size.
+				&bufferWriteAction{Data: "cdefghij"},
+				&bufferCloseAction{},
+			},
+		}, {
+			name:      "record-aware: flush partial line after max hold",
+			size:      10000,
+			delimiter: "\n",
+			maxHold:   2 * time.Second,
+			ops: []interface{}{
+				&WriteOperation{data: "foo\nbar"},
+				&UpdateDurationOperation{elapsed: 1500 * time.Millisecond},
+				&WriteOperation{data: ""}, // Flush "foo\n" and hold "bar".
+				&UpdateDurationOperation{elapsed: 2600 * time.Millisecond},
+				&WriteOperation{data: "baz"}, // Keep holding "barbaz".
+				&UpdateDurationOperation{elapsed: 3600 * time.Millisecond},
+				&WriteOperation{data: ""}, // Flush "barbaz" because 2100ms have passed since "bar" was held.
+				&WriteOperation{data: "qux"},
+				&CloseOperation{},
+			},
+			actions: []interface{}{
+				&bufferWriteAction{Data: "foo\n"},
+				&bufferWriteAction{Data: "barbaz"},
+				&bufferWriteAction{Data: "qux"},
+				&bufferCloseAction{},
+			},
 		},
 	}
 	for _, testCase := range cases {
-		t.Run(testCase.name, func(t *testing.T) {
-			currentTime := time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)
-			elapsed := time.Duration(0)
-			w := &bufferTestWriter{}
-			now := func() time.Time {
-				return currentTime.Add(elapsed)
-			}
-			buf := newBuffer(testCase.size, 1*time.Second, w, now)
+		for _, inFlight := range []int{0, 2} {
+			t.Run(fmt.Sprintf("%s/inFlight=%d", testCase.name, inFlight), func(t *testing.T) {
+				currentTime := time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)
+				elapsed := time.Duration(0)
+				w := &bufferTestWriter{}
+				now := func() time.Time {
+					return currentTime.Add(elapsed)
+				}
+				buf := newBuffer(testCase.size, 1*time.Second, w, now)
+				buf.MaxInFlight = inFlight
+				buf.Delimiter = []byte(testCase.delimiter)
+				buf.MaxHold = testCase.maxHold
 
-			for _, _op := range testCase.ops {
-				switch op := _op.(type) {
-				case *WriteOperation:
-					n, err := buf.Write([]byte(op.data))
-					assert.Equal(t, op.err, err)
-					assert.Equal(t, len(op.data), n)
-				case *CloseOperation:
-					err := buf.Close()
-					assert.Equal(t, op.err, err)
-				case *UpdateDurationOperation:
-					elapsed = op.elapsed
-				default:
-					assert.Failf(t, "unsupported type", "%#+v", _op)
+				for _, _op := range testCase.ops {
+					switch op := _op.(type) {
+					case *WriteOperation:
+						n, err := buf.Write([]byte(op.data))
+						assert.Equal(t, op.err, err)
+						assert.Equal(t, len(op.data), n)
+					case *CloseOperation:
+						err := buf.Close()
+						assert.Equal(t, op.err, err)
+					case *UpdateDurationOperation:
+						elapsed = op.elapsed
+					default:
+						assert.Failf(t, "unsupported type", "%#+v", _op)
+					}
 				}
-			}
 
-			assert.Equal(t, testCase.actions, w.Actions)
-		})
+				assert.Equal(t, testCase.actions, w.Actions)
+			})
+		}
 	}
 }
+
+func TestBuffer_Write_backgroundError(t *testing.T) {
+	ew := &errorWriter{err: errors.New("disk full")}
+	buf := newBuffer(4, time.Second, ew, time.Now)
+	buf.MaxInFlight = 1
+	_, err := buf.Write([]byte("dat"))
+	assert.NoError(t, err)
+	// Flush the buffer in the background.
+	_, _ = buf.Write([]byte("a"))
+	// The error is returned by subsequent calls after the background flush failed.
+	buf.waitBackground()
+	_, err = buf.Write([]byte("x"))
+	assert.Equal(t, ew.err, err)
+	assert.Equal(t, ew.err, buf.Close())
+}
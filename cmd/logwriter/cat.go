@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/yuuki0xff/go-logwriter"
+	"io"
+	"os"
+	"time"
+)
+
+func runCat(args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: logwriter cat [flags] FILE...\n\nDecompress log files and write them to stdout.\n\n")
+		fs.PrintDefaults()
+	}
+	var from, to time.Time
+	fs.Func("from", "write only frames containing data written at or after this time in RFC3339. Requires the time index", func(s string) (err error) {
+		from, err = time.Parse(time.RFC3339, s)
+		return
+	})
+	fs.Func("to", "write only frames containing data written at or before this time in RFC3339. Requires the time index", func(s string) (err error) {
+		to, err = time.Parse(time.RFC3339, s)
+		return
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("no file specified")
+	}
+	return catFiles(os.Stdout, fs.Args(), from, to)
+}
+
+// catFiles writes files to w.
+// If from or to is not zero, only frames in the time range are written by the time index.
+func catFiles(w io.Writer, files []string, from, to time.Time) error {
+	for _, file := range files {
+		var r io.ReadCloser
+		var err error
+		if from.IsZero() && to.IsZero() {
+			r, err = logwriter.OpenReader(file)
+		} else {
+			r, err = logwriter.ReadRange(file, from, to)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuuki0xff/go-logwriter"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLogFile writes data to the file by logwriter.Open.
+func writeLogFile(t *testing.T, file string, data ...string) {
+	opt := logwriter.DefaultOpenOption
+	opt.FileOrDir = file
+	w, err := logwriter.Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, d := range data {
+		_, err = io.WriteString(w, d)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+}
+
+func Test_catFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		filepath.Join(dir, "a.log"),
+		filepath.Join(dir, "b.log.gz"),
+		filepath.Join(dir, "c.log.zst"),
+	}
+	for _, file := range files {
+		writeLogFile(t, file, filepath.Base(file)+"\n")
+	}
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, catFiles(&buf, files, time.Time{}, time.Time{}))
+	assert.Equal(t, "a.log\nb.log.gz\nc.log.zst\n", buf.String())
+
+	assert.Error(t, catFiles(&buf, []string{filepath.Join(dir, "not-found.log.zst")}, time.Time{}, time.Time{}))
+}
+
+func Test_catFiles_timeRange(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.log.zst")
+	opt := logwriter.DefaultOpenOption
+	opt.FileOrDir = file
+	opt.TimeIndex = true
+	w, err := logwriter.Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, "foo\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, catFiles(&buf, []string{file}, time.Now().Add(-time.Hour), time.Time{}))
+	assert.Equal(t, "foo\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, catFiles(&buf, []string{file}, time.Time{}, time.Now().Add(-time.Hour)))
+	assert.Equal(t, "", buf.String())
+
+	// The file has no time index.
+	noIndex := filepath.Join(dir, "b.log.zst")
+	writeLogFile(t, noIndex, "bar\n")
+	assert.Error(t, catFiles(&buf, []string{noIndex}, time.Now(), time.Time{}))
+}
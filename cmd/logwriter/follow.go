@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/yuuki0xff/go-logwriter"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func runFollow(args []string) error {
+	fs := flag.NewFlagSet("follow", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: logwriter follow [flags] DIR\n\nFollow the newest log file in the directory across rotations and restarts of the writer process.\n\n")
+		fs.PrintDefaults()
+	}
+	prefix := fs.String("prefix", logwriter.DefaultOpenOption.Prefix, "prefix of log file names")
+	n := fs.Int("n", 10, "number of lines to write from the newest file before following")
+	interval := fs.Duration("interval", time.Second, "interval to check new frames and new files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("specify a directory")
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	return followDir(os.Stdout, fs.Arg(0), *prefix, *n, *interval, sig)
+}
+
+func followDir(w io.Writer, dir, prefix string, n int, interval time.Duration, sig <-chan os.Signal) error {
+	follower, err := logwriter.Follow(dir, prefix, n, interval)
+	if err != nil {
+		return err
+	}
+	return copyUntilSignal(w, follower, sig)
+}
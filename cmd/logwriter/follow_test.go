@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuuki0xff/go-logwriter"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_followDir(t *testing.T) {
+	dir := t.TempDir()
+	opt := logwriter.DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "app"
+	w, err := logwriter.Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, "line 1\nline 2\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	buf := bytes.Buffer{}
+	sig := make(chan os.Signal, 1)
+	sig <- syscall.SIGINT
+	assert.NoError(t, followDir(&buf, dir, "app", 1, time.Millisecond, sig))
+	assert.Equal(t, "line 2\n", buf.String())
+}
@@ -0,0 +1,51 @@
+// Command logwriter writes stdin to compressed log files in the same format as the logwriter package,
+// and reads them.
+//
+// Usage:
+//
+//	logwriter [write] [flags]
+//	logwriter cat [-from TIME] [-to TIME] FILE...
+//	logwriter tail [-n N] [-f] FILE
+//	logwriter follow [-prefix PREFIX] [-n N] DIR
+//	logwriter merge [-layout LAYOUT] [-utc] [-tag-pid] FILE...
+//	logwriter train-dict [-size N] -o OUTPUT FILE...
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of logwriter.
+type command struct {
+	name string
+	run  func(args []string) error
+}
+
+var commands = []command{
+	{name: "write", run: runWrite},
+	{name: "cat", run: runCat},
+	{name: "tail", run: runTail},
+	{name: "follow", run: runFollow},
+	{name: "merge", run: runMerge},
+	{name: "train-dict", run: runTrainDict},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "logwriter: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if 0 < len(args) {
+		for _, cmd := range commands {
+			if args[0] == cmd.name {
+				return cmd.run(args[1:])
+			}
+		}
+	}
+	// "write" is the default subcommand.
+	return runWrite(args)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/yuuki0xff/go-logwriter"
+	"os"
+	"time"
+)
+
+func runMerge(args []string) error {
+	opt, files, err := parseMergeFlags(args)
+	if err != nil {
+		return err
+	}
+	return logwriter.Merge(os.Stdout, files, opt)
+}
+
+func parseMergeFlags(args []string) (logwriter.MergeOption, []string, error) {
+	var opt logwriter.MergeOption
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: logwriter merge [flags] FILE...\n\nMerge log files and write lines to stdout in chronological order.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opt.TimeLayout, "layout", "", `Go time layout of the timestamp at the beginning of lines (e.g. "2006-01-02T15:04:05Z07:00") (default formats of the log package)`)
+	utc := fs.Bool("utc", false, "parse timestamps without time zone as UTC instead of local time")
+	fs.BoolVar(&opt.TagPid, "tag-pid", false, `prefix each line with "[pid] " taken from the file name`)
+	if err := fs.Parse(args); err != nil {
+		return opt, nil, err
+	}
+	if fs.NArg() == 0 {
+		return opt, nil, errors.New("no file specified")
+	}
+	if *utc {
+		opt.Location = time.UTC
+	}
+	return opt, fs.Args(), nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yuuki0xff/go-logwriter"
+	"testing"
+	"time"
+)
+
+func Test_parseMergeFlags(t *testing.T) {
+	opt, files, err := parseMergeFlags([]string{"-layout", time.RFC3339, "-utc", "-tag-pid", "a.log.zst", "b.log.zst"})
+	assert.NoError(t, err)
+	assert.Equal(t, logwriter.MergeOption{TimeLayout: time.RFC3339, Location: time.UTC, TagPid: true}, opt)
+	assert.Equal(t, []string{"a.log.zst", "b.log.zst"}, files)
+
+	_, _, err = parseMergeFlags(nil)
+	assert.Error(t, err)
+}
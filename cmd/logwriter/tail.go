@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/yuuki0xff/go-logwriter"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func runTail(args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: logwriter tail [flags] FILE\n\nWrite the last lines of a log file to stdout.\n\n")
+		fs.PrintDefaults()
+	}
+	n := fs.Int("n", 10, "number of lines")
+	follow := fs.Bool("f", false, "wait for new frames appended to the file")
+	interval := fs.Duration("interval", time.Second, "interval to check new frames")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("specify a file")
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	return tailFile(os.Stdout, fs.Arg(0), *n, *follow, *interval, sig)
+}
+
+func tailFile(w io.Writer, file string, n int, follow bool, interval time.Duration, sig <-chan os.Signal) error {
+	lines, offset, err := logwriter.Tail(file, n)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(lines); err != nil || !follow {
+		return err
+	}
+
+	follower, err := logwriter.FollowFile(file, offset, interval)
+	if err != nil {
+		return err
+	}
+	return copyUntilSignal(w, follower, sig)
+}
+
+// copyUntilSignal copies r to w until a signal is received, and then closes r.
+func copyUntilSignal(w io.Writer, r io.ReadCloser, sig <-chan os.Signal) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, r)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		r.Close()
+		return err
+	case <-sig:
+		r.Close()
+		// Wait for the data being read is written to w.
+		return <-done
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_tailFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.log.zst")
+	writeLogFile(t, file, "line 1\n", "line 2\n", "line 3\n")
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, tailFile(&buf, file, 2, false, time.Second, nil))
+	assert.Equal(t, "line 2\nline 3\n", buf.String())
+
+	buf.Reset()
+	sig := make(chan os.Signal, 1)
+	sig <- syscall.SIGINT
+	assert.NoError(t, tailFile(&buf, file, 1, true, time.Millisecond, sig))
+	assert.Equal(t, "line 3\n", buf.String())
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/yuuki0xff/go-logwriter"
+	"os"
+	"path/filepath"
+)
+
+func runTrainDict(args []string) error {
+	fs := flag.NewFlagSet("train-dict", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: logwriter train-dict [flags] -o OUTPUT FILE...\n\nCreate a zstd dictionary from log files.\n"+
+			"If OUTPUT is a directory, the dictionary is saved with the name readers look up.\n\n")
+		fs.PrintDefaults()
+	}
+	size := fs.Int("size", logwriter.DefaultDictionarySize, "maximum dictionary size in bytes")
+	output := fs.String("o", "", "output file or directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return errors.New("no output specified")
+	}
+	if fs.NArg() == 0 {
+		return errors.New("no file specified")
+	}
+	_, err := trainDict(*output, fs.Args(), *size)
+	return err
+}
+
+// trainDict creates a dictionary from files and writes it to output.
+// It returns the path of the written dictionary.
+func trainDict(output string, files []string, size int) (string, error) {
+	dict, err := logwriter.TrainDictionary(files, size)
+	if err != nil {
+		return "", err
+	}
+	if stat, err := os.Stat(output); err == nil && stat.IsDir() {
+		id, err := logwriter.DictionaryID(dict)
+		if err != nil {
+			return "", err
+		}
+		output = filepath.Join(output, logwriter.DictionaryFileName(id))
+	}
+	return output, os.WriteFile(output, dict, 0666)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuuki0xff/go-logwriter"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_trainDict(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.log.zst")
+	var data []string
+	for i := 0; i < 100; i++ {
+		data = append(data, fmt.Sprintf("level=info msg=\"request done\" id=%d\n", i))
+	}
+	writeLogFile(t, file, data...)
+
+	// A directory output uses the name readers look up.
+	output, err := trainDict(dir, []string{file}, 1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dict, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	id, err := logwriter.DictionaryID(dict)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, logwriter.DictionaryFileName(id)), output)
+
+	output, err = trainDict(filepath.Join(dir, "custom.dict"), []string{file}, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "custom.dict"), output)
+
+	_, err = trainDict(dir, []string{filepath.Join(dir, "not-found.log.zst")}, 1024)
+	assert.Error(t, err)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/yuuki0xff/go-logwriter"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// overflowPolicies maps names accepted by -overflow to logwriter.OverflowPolicy.
+var overflowPolicies = map[string]logwriter.OverflowPolicy{
+	"block":       logwriter.Block,
+	"drop-newest": logwriter.DropNewest,
+	"drop-oldest": logwriter.DropOldest,
+}
+
+// lockModes maps names accepted by -lock to logwriter.LockMode.
+var lockModes = map[string]logwriter.LockMode{
+	"none":     logwriter.NoLock,
+	"fail":     logwriter.LockFailFast,
+	"wait":     logwriter.LockWait,
+	"fallback": logwriter.LockFallback,
+}
+
+// openFlags maps names accepted by -flag to os.OpenFile flags.
+var openFlags = map[string]int{
+	"rdonly": os.O_RDONLY,
+	"wronly": os.O_WRONLY,
+	"rdwr":   os.O_RDWR,
+	"append": os.O_APPEND,
+	"create": os.O_CREATE,
+	"excl":   os.O_EXCL,
+	"sync":   os.O_SYNC,
+	"trunc":  os.O_TRUNC,
+}
+
+func runWrite(args []string) error {
+	opt, err := parseWriteFlags(args)
+	if err != nil {
+		return err
+	}
+	w, err := logwriter.Open(opt)
+	if err != nil {
+		return err
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sig)
+	return copyAndClose(w, os.Stdin, sig)
+}
+
+// copyAndClose copies r to w until EOF or a signal is received, and then closes w.
+// SIGHUP reopens the file instead of stopping, like logrotate expects.
+func copyAndClose(w io.WriteCloser, r io.Reader, sig <-chan os.Signal) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, r)
+		done <- err
+	}()
+
+	var err error
+loop:
+	for {
+		select {
+		case err = <-done:
+			break loop
+		case s := <-sig:
+			if s != syscall.SIGHUP {
+				// Data read after the signal is dropped. Flush buffered data and exit.
+				break loop
+			}
+			if reopener, ok := w.(logwriter.Reopener); ok {
+				if err := reopener.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "logwriter: failed to reopen: %s\n", err)
+				}
+			}
+		}
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func parseWriteFlags(args []string) (logwriter.OpenOption, error) {
+	opt := logwriter.DefaultOpenOption
+	fs := flag.NewFlagSet("write", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: logwriter [write] [flags]\n\nRead stdin and write it to compressed log files.\nSIGHUP reopens the file, e.g. after logrotate renamed it.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opt.FileOrDir, "dir", opt.FileOrDir, `path to file or directory. "-" means stderr, and "" means discard`)
+	fs.StringVar(&opt.Prefix, "prefix", opt.Prefix, "prefix for generated file names")
+	fs.StringVar(&opt.Suffix, "suffix", opt.Suffix, `suffix for generated file names (".zst", ".gz", ".s2", ".sz", ".zz", ".deflate" or "")`)
+	fs.StringVar(&opt.FilenameTemplate, "filename-template", opt.FilenameTemplate, `template of generated file names followed by -suffix (e.g. "{time:2006/01/02}/{prefix}-{hostname}-{pid}.log")`)
+	fs.Func("flag", "comma separated flags to open a file (rdonly, wronly, rdwr, append, create, excl, sync, trunc) (default wronly,append,create)", func(s string) (err error) {
+		opt.Flag, err = parseOpenFlag(s)
+		return
+	})
+	fs.Func("mode", "file mode in octal (default 0666)", func(s string) error {
+		mode, err := strconv.ParseUint(s, 8, 32)
+		opt.Mode = os.FileMode(mode)
+		return err
+	})
+	fs.IntVar(&opt.BufferSize, "buffer-size", opt.BufferSize, "buffer size in bytes. 0 disables buffering")
+	fs.DurationVar(&opt.FlushInterval, "flush-interval", opt.FlushInterval, "interval to flush the buffer. 0 disables buffering")
+	fs.IntVar(&opt.MaxInFlightFlushes, "max-in-flight-flushes", opt.MaxInFlightFlushes, "number of buffers flushed in the background. 0 flushes in the foreground")
+	fs.StringVar(&opt.RecordDelimiter, "record-delimiter", opt.RecordDelimiter, `cut compressed frames only after this delimiter (e.g. "\n"). Escape sequences are interpreted`)
+	fs.DurationVar(&opt.MaxHold, "max-hold", opt.MaxHold, "how long a partial record is held in the buffer. 0 means no time limit")
+	fs.IntVar(&opt.CompressionWorkers, "compression-workers", opt.CompressionWorkers, "number of goroutines compressing buffers in parallel")
+	fs.IntVar(&opt.Compression.Level, "compression-level", opt.Compression.Level, "compression level (0 means the default of the algorithm)")
+	fs.IntVar(&opt.Compression.WindowSize, "window-size", opt.Compression.WindowSize, "compression window size in bytes (zstd only)")
+	fs.Func("dictionary", "path to a dictionary created by the train-dict command (zstd only)", func(s string) (err error) {
+		opt.Compression.Dictionary, err = os.ReadFile(s)
+		return
+	})
+	fs.BoolVar(&opt.StreamCompression, "stream-compression", opt.StreamCompression, "keep one compression stream across flushes instead of a frame per flush (not supported by .s2 and .sz)")
+	fs.Int64Var(&opt.MaxFrameSize, "max-frame-size", opt.MaxFrameSize, "limit of uncompressed bytes in a frame with -stream-compression (default 1MiB)")
+	fs.BoolVar(&opt.Seekable, "seekable", opt.Seekable, "write a seek table at the end of each file for random access (zstd only)")
+	fs.BoolVar(&opt.TimeIndex, "time-index", opt.TimeIndex, "record the time range of each frame to FILE.idx for cat -from/-to")
+	fs.Int64Var(&opt.MaxFileSize, "max-file-size", opt.MaxFileSize, "switch to a new file when the file size reaches this size in bytes. 0 disables size-based rotation")
+	fs.BoolVar(&opt.UncompressedFileSize, "uncompressed-file-size", opt.UncompressedFileSize, "compare -max-file-size with the size of data before compression")
+	fs.DurationVar(&opt.RotateSchedule.Interval, "rotate-interval", opt.RotateSchedule.Interval, "switch to a new file at every boundary of this interval (e.g. 1h, 24h). 0 disables time-based rotation")
+	fs.Func("rotate-epoch", "origin of -rotate-interval boundaries in RFC3339 (default Unix epoch)", func(s string) (err error) {
+		opt.RotateSchedule.Epoch, err = time.Parse(time.RFC3339, s)
+		return
+	})
+	rotateLocal := fs.Bool("rotate-local", false, "align -rotate-interval boundaries to midnight in local time")
+	fs.IntVar(&opt.MaxFiles, "max-files", opt.MaxFiles, "number of log files to keep. 0 means unlimited")
+	fs.DurationVar(&opt.MaxAge, "max-age", opt.MaxAge, "remove log files older than this. 0 means unlimited")
+	fs.Int64Var(&opt.MaxTotalBytes, "max-total-bytes", opt.MaxTotalBytes, "total size of log files to keep in bytes. 0 means unlimited")
+	fs.BoolVar(&opt.WatchFile, "watch-file", opt.WatchFile, "reopen the file when it was removed, renamed or replaced. The file is checked at every -flush-interval")
+	fs.BoolVar(&opt.CurrentLink, "current-link", opt.CurrentLink, "maintain the symlink PREFIX.current.logSUFFIX pointing at the file being written")
+	fs.BoolVar(&opt.RepairOnOpen, "repair", opt.RepairOnOpen, "truncate a damaged final frame before appending to an existing file")
+	fs.Func("lock", "lock the file against other processes (none, fail, wait, fallback) (default none)", func(s string) error {
+		mode, ok := lockModes[s]
+		if !ok {
+			return fmt.Errorf("unknown lock mode: %q", s)
+		}
+		opt.LockMode = mode
+		return nil
+	})
+	fs.IntVar(&opt.AsyncQueueSize, "async-queue-size", opt.AsyncQueueSize, "queue size in bytes for asynchronous writing. 0 disables asynchronous writing")
+	fs.Func("overflow", "behavior when the queue is full (block, drop-newest, drop-oldest) (default block)", func(s string) error {
+		policy, ok := overflowPolicies[s]
+		if !ok {
+			return fmt.Errorf("unknown policy: %q", s)
+		}
+		opt.OverflowPolicy = policy
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return opt, err
+	}
+	if 0 < fs.NArg() {
+		return opt, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+	if opt.RecordDelimiter != "" {
+		delimiter, err := strconv.Unquote(`"` + opt.RecordDelimiter + `"`)
+		if err != nil {
+			return opt, fmt.Errorf("invalid -record-delimiter: %w", err)
+		}
+		opt.RecordDelimiter = delimiter
+	}
+	if *rotateLocal {
+		if !opt.RotateSchedule.Epoch.IsZero() {
+			return opt, errors.New("-rotate-local and -rotate-epoch are exclusive")
+		}
+		opt.RotateSchedule.Epoch = logwriter.DailyRotationIn(time.Local).Epoch
+	}
+	return opt, nil
+}
+
+func parseOpenFlag(s string) (int, error) {
+	var flag int
+	for _, name := range strings.Split(s, ",") {
+		f, ok := openFlags[strings.TrimSpace(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown flag: %q", name)
+		}
+		flag |= f
+	}
+	return flag, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/yuuki0xff/go-logwriter"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_parseWriteFlags(t *testing.T) {
+	opt, err := parseWriteFlags([]string{
+		"-dir", "/var/log/app",
+		"-prefix", "app",
+		"-suffix", ".gz",
+		"-filename-template", "{prefix}-{seq}.log",
+		"-flag", "wronly,create,excl",
+		"-mode", "0640",
+		"-buffer-size", "1024",
+		"-flush-interval", "5s",
+		"-max-in-flight-flushes", "2",
+		"-record-delimiter", `\n`,
+		"-max-hold", "10s",
+		"-compression-workers", "4",
+		"-compression-level", "9",
+		"-stream-compression",
+		"-max-frame-size", "65536",
+		"-seekable",
+		"-time-index",
+		"-max-file-size", "1000000",
+		"-uncompressed-file-size",
+		"-rotate-interval", "1h",
+		"-max-files", "10",
+		"-max-age", "168h",
+		"-max-total-bytes", "1000000000",
+		"-repair",
+		"-lock", "fallback",
+		"-watch-file",
+		"-current-link",
+		"-async-queue-size", "1048576",
+		"-overflow", "drop-oldest",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, logwriter.OpenOption{
+		FileOrDir:            "/var/log/app",
+		Prefix:               "app",
+		Suffix:               ".gz",
+		FilenameTemplate:     "{prefix}-{seq}.log",
+		Flag:                 os.O_WRONLY | os.O_CREATE | os.O_EXCL,
+		Mode:                 0640,
+		BufferSize:           1024,
+		FlushInterval:        5 * time.Second,
+		MaxInFlightFlushes:   2,
+		RecordDelimiter:      "\n",
+		MaxHold:              10 * time.Second,
+		CompressionWorkers:   4,
+		Compression:          logwriter.CompressionOption{Level: 9},
+		StreamCompression:    true,
+		MaxFrameSize:         65536,
+		Seekable:             true,
+		TimeIndex:            true,
+		MaxFileSize:          1000000,
+		UncompressedFileSize: true,
+		RotateSchedule:       logwriter.HourlyRotation,
+		MaxFiles:             10,
+		MaxAge:               168 * time.Hour,
+		MaxTotalBytes:        1000000000,
+		RepairOnOpen:         true,
+		LockMode:             logwriter.LockFallback,
+		WatchFile:            true,
+		CurrentLink:          true,
+		AsyncQueueSize:       1048576,
+		OverflowPolicy:       logwriter.DropOldest,
+	}, opt)
+
+	opt, err = parseWriteFlags(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, logwriter.DefaultOpenOption, opt)
+
+	for _, args := range [][]string{
+		{"-flag", "wronly,unknown"},
+		{"-mode", "rw-rw-rw-"},
+		{"-overflow", "unknown"},
+		{"-lock", "unknown"},
+		{"-dictionary", "not-found.zdict"},
+		{"-record-delimiter", `\`},
+		{"-rotate-local", "-rotate-epoch", "2000-01-01T00:00:00Z"},
+		{"extra-argument"},
+	} {
+		_, err = parseWriteFlags(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func Test_copyAndClose(t *testing.T) {
+	t.Run("EOF", func(t *testing.T) {
+		dir := t.TempDir()
+		opt, err := parseWriteFlags([]string{"-dir", dir, "-prefix", "test"})
+		assert.NoError(t, err)
+		w, err := logwriter.Open(opt)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, copyAndClose(w, strings.NewReader("hello\n"), nil))
+		assert.Equal(t, "hello\n", readLogFiles(t, dir))
+	})
+	t.Run("signal", func(t *testing.T) {
+		dir := t.TempDir()
+		opt, err := parseWriteFlags([]string{"-dir", dir, "-prefix", "test"})
+		assert.NoError(t, err)
+		w, err := logwriter.Open(opt)
+		if !assert.NoError(t, err) {
+			return
+		}
+		r, pw := io.Pipe()
+		defer pw.Close()
+		sig := make(chan os.Signal, 1)
+		go func() {
+			io.WriteString(pw, "hello\n")
+			// Wait until the data is passed to w. Empty write returns after the next Read call.
+			pw.Write(nil)
+			sig <- syscall.SIGTERM
+		}()
+		assert.NoError(t, copyAndClose(w, r, sig))
+		assert.Equal(t, "hello\n", readLogFiles(t, dir))
+	})
+	t.Run("SIGHUP", func(t *testing.T) {
+		dir := t.TempDir()
+		filePath := filepath.Join(dir, "test.log.zst")
+		opt, err := parseWriteFlags([]string{"-dir", filePath})
+		assert.NoError(t, err)
+		w, err := logwriter.Open(opt)
+		if !assert.NoError(t, err) {
+			return
+		}
+		r, pw := io.Pipe()
+		defer pw.Close()
+		sig := make(chan os.Signal)
+		rotated := filepath.Join(dir, "test.1.log.zst")
+		go func() {
+			io.WriteString(pw, "foo\n")
+			pw.Write(nil)
+			// Rotated by logrotate.
+			os.Rename(filePath, rotated)
+			sig <- syscall.SIGHUP
+			// The second signal is received after the first Reopen returned.
+			sig <- syscall.SIGHUP
+			io.WriteString(pw, "bar\n")
+			pw.Write(nil)
+			sig <- syscall.SIGTERM
+		}()
+		assert.NoError(t, copyAndClose(w, r, sig))
+		assert.Equal(t, "foo\n", readLogFile(t, rotated))
+		assert.Equal(t, "bar\n", readLogFile(t, filePath))
+	})
+}
+
+func readLogFiles(t *testing.T, dir string) string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.log*"))
+	assert.NoError(t, err)
+	var data string
+	for _, file := range files {
+		data += readLogFile(t, file)
+	}
+	return data
+}
+
+func readLogFile(t *testing.T, file string) string {
+	r, err := logwriter.OpenReader(file)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	return string(b)
+}
@@ -0,0 +1,241 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DictionaryExt is the extension of dictionary files.
+// Readers look up dictionary files in the directory of the log file.
+const DictionaryExt = ".zdict"
+
+// DefaultDictionarySize is the default size of the dictionary created by TrainDictionary.
+const DefaultDictionarySize = 64 << 10
+
+// errNoSamples is returned if there is no data to train the dictionary.
+var errNoSamples = errors.New("no samples to train the dictionary")
+
+// DictionaryID returns the ID of the zstd dictionary.
+func DictionaryID(dict []byte) (uint32, error) {
+	d, err := zstd.InspectDictionary(dict)
+	if err != nil {
+		return 0, fmt.Errorf("invalid dictionary: %w", err)
+	}
+	return d.ID(), nil
+}
+
+// DictionaryFileName returns the file name of the dictionary with the ID.
+func DictionaryFileName(id uint32) string {
+	return fmt.Sprintf("%08x%s", id, DictionaryExt)
+}
+
+// TrainDictionary creates a zstd dictionary from log files.
+// Each frame in the files is used as a sample, so files written with a short FlushInterval make good samples.
+// If size is not a positive value, DefaultDictionarySize is used.
+func TrainDictionary(files []string, size int) ([]byte, error) {
+	var samples [][]byte
+	for _, file := range files {
+		s, err := readSamples(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		samples = append(samples, s...)
+	}
+	return BuildDictionary(samples, size)
+}
+
+// BuildDictionary creates a zstd dictionary from samples.
+// Lines which appear in many samples are stored in the dictionary.
+// If size is not a positive value, DefaultDictionarySize is used.
+func BuildDictionary(samples [][]byte, size int) ([]byte, error) {
+	if size <= 0 {
+		size = DefaultDictionarySize
+	}
+	var contents [][]byte
+	for _, s := range samples {
+		if 0 < len(s) {
+			contents = append(contents, s)
+		}
+	}
+	if len(contents) == 0 {
+		return nil, errNoSamples
+	}
+	history := dictionaryHistory(contents, size)
+	return zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       dictionaryIDOf(history),
+		Contents: contents,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+	})
+}
+
+// dictionaryHistory selects the content of the dictionary.
+// Frequent lines are placed at the end of the history because closer matches are cheaper.
+func dictionaryHistory(samples [][]byte, size int) []byte {
+	counts := map[string]int{}
+	for _, s := range samples {
+		for 0 < len(s) {
+			line := s
+			if i := bytes.IndexByte(s, '\n'); 0 <= i {
+				line = s[:i+1]
+			}
+			counts[string(line)]++
+			s = s[len(line):]
+		}
+	}
+	lines := make([]string, 0, len(counts))
+	for line, n := range counts {
+		if 1 < n {
+			lines = append(lines, line)
+		}
+	}
+	// Lines which save more bytes come first.
+	sort.Slice(lines, func(i, j int) bool {
+		si, sj := counts[lines[i]]*len(lines[i]), counts[lines[j]]*len(lines[j])
+		if si != sj {
+			return si > sj
+		}
+		return lines[i] < lines[j]
+	})
+
+	var selected []string
+	total := 0
+	for _, line := range lines {
+		if size < total+len(line) {
+			continue
+		}
+		selected = append(selected, line)
+		total += len(line)
+	}
+
+	// Fill the remaining space with the latest samples.
+	var fill [][]byte
+	for i := len(samples) - 1; 0 <= i && total < size; i-- {
+		s := samples[i]
+		if remain := size - total; remain < len(s) {
+			s = s[len(s)-remain:]
+		}
+		fill = append(fill, s)
+		total += len(s)
+	}
+
+	history := make([]byte, 0, max(total, 8))
+	// zstd requires at least 8 bytes.
+	for i := total; i < 8; i++ {
+		history = append(history, ' ')
+	}
+	for i := len(fill) - 1; 0 <= i; i-- {
+		history = append(history, fill[i]...)
+	}
+	for i := len(selected) - 1; 0 <= i; i-- {
+		history = append(history, selected[i]...)
+	}
+	return history
+}
+
+// dictionaryIDOf derives the dictionary ID from the content.
+// The ID is in the range recommended for user dictionaries.
+func dictionaryIDOf(history []byte) uint32 {
+	const minID = 1 << 15
+	const maxID = 1 << 31
+	return minID + crc32.ChecksumIEEE(history)%(maxID-minID)
+}
+
+// readSamples reads each frame in the file as a sample.
+func readSamples(file string) ([][]byte, error) {
+	a := readerAlgorithm(file)
+	fa, ok := a.(FrameAlgorithm)
+	if !ok {
+		r, err := OpenReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		return [][]byte{data}, err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var samples [][]byte
+	buf := bytes.Buffer{}
+	for {
+		buf.Reset()
+		_, err = fa.ReadFrame(br, &buf)
+		if err == io.EOF {
+			return samples, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if 0 < buf.Len() {
+			samples = append(samples, bytes.Clone(buf.Bytes()))
+		}
+	}
+}
+
+// saveDictionary writes the dictionary to dir unless it already exists.
+func saveDictionary(dir string, dict []byte) error {
+	id, err := DictionaryID(dict)
+	if err != nil {
+		return err
+	}
+	filePath := filepath.Join(dir, DictionaryFileName(id))
+	if old, err := os.ReadFile(filePath); err == nil {
+		if !bytes.Equal(old, dict) {
+			return fmt.Errorf("%s: another dictionary has the same ID", filePath)
+		}
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Write to a temporary file and rename it to prevent readers from loading a partial dictionary.
+	f, err := os.CreateTemp(dir, ".dict-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(dict)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filePath)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// loadDictionaries reads all dictionary files in dir.
+func loadDictionaries(dir string) ([][]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+DictionaryExt))
+	if err != nil {
+		return nil, err
+	}
+	var dicts [][]byte
+	for _, file := range files {
+		dict, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = DictionaryID(dict); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		dicts = append(dicts, dict)
+	}
+	return dicts, nil
+}
@@ -0,0 +1,109 @@
+package logwriter
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sampleFrames returns small frames which look like access logs.
+func sampleFrames(n int) []string {
+	frames := make([]string, n)
+	for i := range frames {
+		frames[i] = fmt.Sprintf(`{"level":"info","method":"GET","path":"/api/v1/users/%d","status":200,"latency_ms":%d}`+"\n", i, i%17)
+	}
+	return frames
+}
+
+func TestTrainDictionary(t *testing.T) {
+	dir := t.TempDir()
+	samplePath := filepath.Join(dir, "sample.log.zst")
+	frames := sampleFrames(200)
+	assert.NoError(t, os.WriteFile(samplePath, compressFrames(t, &ZstdAlgorithm{}, frames), 0666))
+
+	dict, err := TrainDictionary([]string{samplePath}, 4096)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.LessOrEqual(t, len(dict), 4096+1024)
+	id, err := DictionaryID(dict)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, id, uint32(1<<15))
+
+	// Small frames shrink with the dictionary.
+	z := &ZstdAlgorithm{}
+	assert.NoError(t, z.Configure(CompressionOption{Dictionary: dict}))
+	withDict := compressFrames(t, z, frames)
+	withoutDict := compressFrames(t, &ZstdAlgorithm{}, frames)
+	assert.Less(t, len(withDict)*2, len(withoutDict))
+
+	_, err = TrainDictionary([]string{filepath.Join(dir, "not-found.log.zst")}, 0)
+	assert.Error(t, err)
+	_, err = BuildDictionary(nil, 0)
+	assert.ErrorIs(t, err, errNoSamples)
+	_, err = DictionaryID([]byte("not a dictionary"))
+	assert.Error(t, err)
+}
+
+func TestOpen_Dictionary(t *testing.T) {
+	dict, err := BuildDictionary(toBytes(sampleFrames(100)), 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	id, _ := DictionaryID(dict)
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "test.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.Compression.Dictionary = dict
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, sampleFrames(1)[0])
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	// The dictionary is saved next to the log file.
+	saved, err := os.ReadFile(filepath.Join(dir, DictionaryFileName(id)))
+	assert.NoError(t, err)
+	assert.Equal(t, dict, saved)
+	// Opening again does not fail.
+	w, err = Open(opt)
+	if assert.NoError(t, err) {
+		assert.NoError(t, w.Close())
+	}
+
+	r, err := OpenReader(filePath)
+	if assert.NoError(t, err) {
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, sampleFrames(1)[0], string(data))
+		r.Close()
+	}
+
+	// The reader cannot decompress frames without the dictionary.
+	assert.NoError(t, os.Remove(filepath.Join(dir, DictionaryFileName(id))))
+	r, err = OpenReader(filePath)
+	if assert.NoError(t, err) {
+		_, err = io.ReadAll(r)
+		assert.Error(t, err)
+		r.Close()
+	}
+
+	// Dictionary is only supported by zstd.
+	opt.FileOrDir = filepath.Join(dir, "test.log.gz")
+	_, err = Open(opt)
+	assert.Error(t, err)
+}
+
+func toBytes(s []string) [][]byte {
+	b := make([][]byte, len(s))
+	for i := range s {
+		b[i] = []byte(s[i])
+	}
+	return b
+}
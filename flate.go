@@ -0,0 +1,167 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/zlib"
+	"io"
+	"sync"
+)
+
+var _ FrameAlgorithm = &DeflateAlgorithm{}
+var _ ConfigurableAlgorithm = &DeflateAlgorithm{}
+
+// NewDeflateAlgorithm returns DeflateAlgorithm with the compression level (e.g. flate.BestSpeed).
+// If level is 0, flate.DefaultCompression is used.
+func NewDeflateAlgorithm(level int) (*DeflateAlgorithm, error) {
+	d := &DeflateAlgorithm{}
+	if err := d.Configure(CompressionOption{Level: level}); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeflateAlgorithm compresses data in the raw deflate format (RFC 1951) without headers.
+type DeflateAlgorithm struct {
+	once  sync.Once
+	level int
+	fw    *flate.Writer
+	fr    io.ReadCloser
+	err   error
+}
+
+func (d *DeflateAlgorithm) Configure(opt CompressionOption) error {
+	if err := opt.levelOnly(); err != nil {
+		return err
+	}
+	d.level = opt.Level
+	d.once.Do(d.init)
+	return d.err
+}
+
+func (d *DeflateAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
+	d.once.Do(d.init)
+	if d.err != nil {
+		return d.err
+	}
+	return compressStream(d.fw, in, out)
+}
+
+func (d *DeflateAlgorithm) Decompress(in []byte, out *bytes.Buffer) error {
+	return decompressFrames(d, in, out)
+}
+
+// ReadFrame reads a deflate stream terminated by the final block.
+func (d *DeflateAlgorithm) ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error) {
+	if _, err := r.Peek(1); err != nil {
+		return 0, err
+	}
+	cr := &countReader{r: r}
+	if d.fr == nil {
+		d.fr = flate.NewReader(cr)
+	} else if err := d.fr.(flate.Resetter).Reset(cr, nil); err != nil {
+		return 0, err
+	}
+	_, err := io.Copy(out, d.fr)
+	return cr.n, err
+}
+
+func (d *DeflateAlgorithm) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	d.once.Do(d.init)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return flate.NewWriter(w, d.compressionLevel())
+}
+
+func (d *DeflateAlgorithm) init() {
+	d.fw, d.err = flate.NewWriter(Discard, d.compressionLevel())
+}
+
+func (d *DeflateAlgorithm) compressionLevel() int {
+	if d.level == 0 {
+		return flate.DefaultCompression
+	}
+	return d.level
+}
+
+var _ FrameAlgorithm = &ZlibAlgorithm{}
+var _ ConfigurableAlgorithm = &ZlibAlgorithm{}
+
+// NewZlibAlgorithm returns ZlibAlgorithm with the compression level (e.g. zlib.BestSpeed).
+// If level is 0, zlib.DefaultCompression is used.
+func NewZlibAlgorithm(level int) (*ZlibAlgorithm, error) {
+	z := &ZlibAlgorithm{}
+	if err := z.Configure(CompressionOption{Level: level}); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// ZlibAlgorithm compresses data in the zlib format (RFC 1950).
+type ZlibAlgorithm struct {
+	once  sync.Once
+	level int
+	zw    *zlib.Writer
+	zr    io.ReadCloser
+	err   error
+}
+
+func (z *ZlibAlgorithm) Configure(opt CompressionOption) error {
+	if err := opt.levelOnly(); err != nil {
+		return err
+	}
+	z.level = opt.Level
+	z.once.Do(z.init)
+	return z.err
+}
+
+func (z *ZlibAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
+	z.once.Do(z.init)
+	if z.err != nil {
+		return z.err
+	}
+	return compressStream(z.zw, in, out)
+}
+
+func (z *ZlibAlgorithm) Decompress(in []byte, out *bytes.Buffer) error {
+	return decompressFrames(z, in, out)
+}
+
+// ReadFrame reads a zlib stream.
+func (z *ZlibAlgorithm) ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error) {
+	if _, err := r.Peek(1); err != nil {
+		return 0, err
+	}
+	cr := &countReader{r: r}
+	var err error
+	if z.zr == nil {
+		z.zr, err = zlib.NewReader(cr)
+	} else {
+		err = z.zr.(zlib.Resetter).Reset(cr, nil)
+	}
+	if err == nil {
+		_, err = io.Copy(out, z.zr)
+	}
+	return cr.n, err
+}
+
+func (z *ZlibAlgorithm) NewEncoder(w io.Writer) (StreamEncoder, error) {
+	z.once.Do(z.init)
+	if z.err != nil {
+		return nil, z.err
+	}
+	return zlib.NewWriterLevel(w, z.compressionLevel())
+}
+
+func (z *ZlibAlgorithm) init() {
+	z.zw, z.err = zlib.NewWriterLevel(Discard, z.compressionLevel())
+}
+
+func (z *ZlibAlgorithm) compressionLevel() int {
+	if z.level == 0 {
+		return zlib.DefaultCompression
+	}
+	return z.level
+}
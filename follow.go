@@ -0,0 +1,277 @@
+package logwriter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FollowFile returns a FileFollower which reads a log file from offset while other process is appending to it.
+// Use the offset returned by Tail to skip existing data, or 0 to read from the beginning.
+// The file is polled at the specified interval.
+func FollowFile(filePath string, offset int64, interval time.Duration) (*FileFollower, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &FileFollower{
+		f:        f,
+		a:        readerAlgorithm(filePath),
+		offset:   offset,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// FileFollower reads a log file like "tail -f".
+// Read blocks until a complete frame is appended to the file.
+// After Close is called, Read returns io.EOF.
+type FileFollower struct {
+	f        *os.File
+	a        Algorithm
+	offset   int64
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// mux protects f from concurrent Read and Close method calls.
+	mux    sync.Mutex
+	buf    bytes.Buffer
+	err    error
+	closed bool
+}
+
+func (f *FileFollower) Read(p []byte) (int, error) {
+	for {
+		n, wait, err := f.read(p)
+		if !wait {
+			return n, err
+		}
+		timer := time.NewTimer(f.interval)
+		select {
+		case <-f.ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Offset returns the end of the last frame read from the file.
+func (f *FileFollower) Offset() int64 {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.offset
+}
+
+func (f *FileFollower) Close() error {
+	f.cancel()
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	return f.f.Close()
+}
+
+// read reads the next frames. If no complete frame is available, it returns wait=true.
+func (f *FileFollower) read(p []byte) (n int, wait bool, err error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if 0 < f.buf.Len() {
+		n, _ = f.buf.Read(p)
+		return n, false, nil
+	}
+	if f.closed {
+		return 0, false, io.EOF
+	}
+	if f.err != nil {
+		return 0, false, f.err
+	}
+
+	if _, err = f.f.Seek(f.offset, io.SeekStart); err != nil {
+		f.err = err
+		return 0, false, err
+	}
+	r := NewTolerantReader(f.f, f.a)
+	// Read a frame at least. Skippable frames and empty frames produce no data.
+	for f.buf.Len() == 0 && r.err == nil {
+		r.readFrame()
+		f.buf.Write(r.buf.Bytes())
+	}
+	f.offset += r.Offset()
+	if d := r.Damage(); d != nil && !d.Truncated() {
+		d.Offset += f.offset - r.Offset()
+		f.err = d
+	}
+	if f.buf.Len() == 0 {
+		return 0, f.err == nil, f.err
+	}
+	n, _ = f.buf.Read(p)
+	return n, false, nil
+}
+
+// Follow returns a DirFollower which reads log files in the directory like "tail -F".
+// It starts with the last n lines of the newest file matching the prefix, and when a newer file is created
+// by rotation or restart of the writer process, it continues reading from the beginning of the new file.
+// The directory is polled at the specified interval, so it works without inotify.
+func Follow(dir, prefix string, n int, interval time.Duration) (*DirFollower, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &DirFollower{
+		dir:      dir,
+		prefix:   prefix,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	files, err := d.list()
+	if err != nil {
+		return nil, err
+	}
+	if 0 < len(files) {
+		newest := files[len(files)-1]
+		lines, offset, err := Tail(newest.path, n)
+		if err != nil {
+			return nil, err
+		}
+		d.buf.Write(lines)
+		if err = d.open(newest, offset); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// DirFollower reads log files in the directory.
+// Read blocks until a complete frame is appended to the file.
+// After Close is called, Read returns io.EOF.
+type DirFollower struct {
+	dir      string
+	prefix   string
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// mux protects current from concurrent Read and Close method calls.
+	mux sync.Mutex
+	// current is nil until a log file is found.
+	current     *FileFollower
+	currentFile logFile
+	buf         bytes.Buffer
+	closed      bool
+}
+
+func (d *DirFollower) Read(p []byte) (int, error) {
+	for {
+		n, wait, err := d.read(p)
+		if !wait {
+			return n, err
+		}
+		timer := time.NewTimer(d.interval)
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// File returns the path to the file currently being read.
+func (d *DirFollower) File() string {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return d.currentFile.path
+}
+
+func (d *DirFollower) Close() error {
+	d.cancel()
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if d.closed {
+		return os.ErrClosed
+	}
+	d.closed = true
+	if d.current != nil {
+		return d.current.Close()
+	}
+	return nil
+}
+
+func (d *DirFollower) read(p []byte) (n int, wait bool, err error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if 0 < d.buf.Len() {
+		n, _ = d.buf.Read(p)
+		return n, false, nil
+	}
+	if d.closed {
+		return 0, false, io.EOF
+	}
+
+	for {
+		if d.current != nil {
+			n, wait, err = d.current.read(p)
+			if !wait {
+				return n, false, err
+			}
+		}
+		// No more data in the current file. Switch to the next file if exists.
+		next, ok, err := d.next()
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			return 0, true, nil
+		}
+		if d.current != nil {
+			d.current.Close()
+		}
+		if err = d.open(next, 0); err != nil {
+			return 0, false, err
+		}
+	}
+}
+
+// next returns the oldest file which is newer than the current file.
+func (d *DirFollower) next() (logFile, bool, error) {
+	files, err := d.list()
+	if err != nil {
+		return logFile{}, false, err
+	}
+	for _, f := range files {
+		if d.current == nil || d.currentFile.before(f) {
+			return f, true, nil
+		}
+	}
+	return logFile{}, false, nil
+}
+
+func (d *DirFollower) open(f logFile, offset int64) error {
+	follower, err := FollowFile(f.path, offset, d.interval)
+	if err != nil {
+		return err
+	}
+	d.current = follower
+	d.currentFile = f
+	return nil
+}
+
+// list returns log files sorted from oldest to newest.
+func (d *DirFollower) list() ([]logFile, error) {
+	var files []logFile
+	for _, suffix := range suffixes() {
+		f, err := listLogFiles(d.dir, d.prefix, suffix)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f...)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].before(files[j])
+	})
+	return files, nil
+}
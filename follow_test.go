@@ -0,0 +1,150 @@
+package logwriter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileFollower_Read(t *testing.T) {
+	for _, suffix := range []string{".gz", ".zst"} {
+		t.Run(suffix, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "test.log"+suffix)
+			a := readerAlgorithm(filePath)
+			f, err := os.Create(filePath)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer f.Close()
+			_, err = f.Write(compressFrames(t, a, []string{"old\n"}))
+			assert.NoError(t, err)
+
+			_, offset, err := Tail(filePath, 0)
+			assert.NoError(t, err)
+			follower, err := FollowFile(filePath, offset, 10*time.Millisecond)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			received := make(chan string)
+			go func() {
+				buf := make([]byte, 100)
+				for {
+					n, err := follower.Read(buf)
+					if err != nil {
+						close(received)
+						return
+					}
+					received <- string(buf[:n])
+				}
+			}()
+
+			// Write a frame in two steps.
+			frame := compressFrames(t, a, []string{"new\n"})
+			_, err = f.Write(frame[:5])
+			assert.NoError(t, err)
+			select {
+			case data := <-received:
+				assert.Failf(t, "incomplete frame was read", "%q", data)
+			case <-time.After(50 * time.Millisecond):
+			}
+			_, err = f.Write(frame[5:])
+			assert.NoError(t, err)
+			select {
+			case data := <-received:
+				assert.Equal(t, "new\n", data)
+			case <-time.After(time.Second):
+				assert.FailNow(t, "timeout exceeded")
+			}
+			assert.Equal(t, offset+int64(len(frame)), follower.Offset())
+
+			assert.NoError(t, follower.Close())
+			_, ok := <-received
+			assert.False(t, ok)
+			_, err = follower.Read(nil)
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestDirFollower_Read(t *testing.T) {
+	dir := t.TempDir()
+	opt := OpenOption{FileOrDir: dir, Prefix: "app", Suffix: ".zst"}
+	now := time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)
+	appendFrame := func(filePath string, data string) {
+		f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer f.Close()
+		_, err = f.Write(compressFrames(t, &ZstdAlgorithm{}, []string{data}))
+		assert.NoError(t, err)
+	}
+	first := generateFilePath(opt, now)
+	appendFrame(first, "line 1\nline 2\n")
+	// Not a log file.
+	appendFrame(filepath.Join(dir, "app.log.zst"), "ignored\n")
+
+	follower, err := Follow(dir, "app", 1, 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer follower.Close()
+	assert.Equal(t, first, follower.File())
+	read := func() string {
+		buf := make([]byte, 100)
+		n, err := follower.Read(buf)
+		assert.NoError(t, err)
+		return string(buf[:n])
+	}
+	assert.Equal(t, "line 2\n", read())
+
+	appendFrame(first, "line 3\n")
+	assert.Equal(t, "line 3\n", read())
+
+	// The writer process was restarted, and created new files.
+	second := generateFilePath(opt, now.Add(time.Minute))
+	third := generateFilePath(OpenOption{FileOrDir: dir, Prefix: "app", Suffix: ".gz"}, now.Add(2*time.Minute))
+	appendFrame(second, "line 4\n")
+	f, err := os.Create(third)
+	assert.NoError(t, err)
+	_, err = f.Write(compressFrames(t, &GzipAlgorithm{}, []string{"line 5\n"}))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	assert.Equal(t, "line 4\n", read())
+	assert.Equal(t, second, follower.File())
+	assert.Equal(t, "line 5\n", read())
+	assert.Equal(t, third, follower.File())
+
+	assert.NoError(t, follower.Close())
+	_, err = follower.Read(nil)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDirFollower_Read_emptyDir(t *testing.T) {
+	dir := t.TempDir()
+	follower, err := Follow(dir, "app", 10, 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer follower.Close()
+
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "app"
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, "hello\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	buf := make([]byte, 100)
+	n, err := follower.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(buf[:n]))
+}
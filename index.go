@@ -0,0 +1,256 @@
+package logwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// IndexExt is the extension of time index files.
+// The index of "app.log.zst" is "app.log.zst.idx".
+const IndexExt = ".idx"
+
+// indexRecordSize is the size of an encoded IndexRecord.
+const indexRecordSize = 40
+
+// IndexRecord is the position and the time range of a frame.
+type IndexRecord struct {
+	// Offset is the position of the frame in the log file.
+	Offset int64
+	// Size is the compressed size of the frame.
+	Size int64
+	// Length is the uncompressed size of the frame.
+	Length int64
+	// First and Last are the time of the first and the last write of the data in the frame.
+	First time.Time
+	Last  time.Time
+}
+
+// overlaps reports whether the frame contains data written in [from, to].
+// Zero from or to means unbounded.
+func (r IndexRecord) overlaps(from, to time.Time) bool {
+	return (from.IsZero() || !r.Last.Before(from)) && (to.IsZero() || !r.First.After(to))
+}
+
+func (r IndexRecord) appendBinary(buf []byte) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.Offset))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.Size))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.Length))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(r.First.UnixNano()))
+	return binary.LittleEndian.AppendUint64(buf, uint64(r.Last.UnixNano()))
+}
+
+func parseIndexRecord(b []byte) IndexRecord {
+	return IndexRecord{
+		Offset: int64(binary.LittleEndian.Uint64(b)),
+		Size:   int64(binary.LittleEndian.Uint64(b[8:])),
+		Length: int64(binary.LittleEndian.Uint64(b[16:])),
+		First:  time.Unix(0, int64(binary.LittleEndian.Uint64(b[24:]))),
+		Last:   time.Unix(0, int64(binary.LittleEndian.Uint64(b[32:]))),
+	}
+}
+
+// ReadIndex reads the time index of the log file.
+// A partial record at the end of the index written by a crashed process is ignored.
+func ReadIndex(filePath string) ([]IndexRecord, error) {
+	data, err := os.ReadFile(filePath + IndexExt)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]IndexRecord, 0, len(data)/indexRecordSize)
+	for ; indexRecordSize <= len(data); data = data[indexRecordSize:] {
+		records = append(records, parseIndexRecord(data))
+	}
+	return records, nil
+}
+
+// timedWriter is implemented by writers which use the time range of the data.
+type timedWriter interface {
+	// WriteTimed writes p which was written to the buffer in [first, last].
+	// Zero first and last mean the current time.
+	WriteTimed(p []byte, first, last time.Time) (int, error)
+}
+
+// writeTimed writes p to w with the time range if w supports it.
+func writeTimed(w io.Writer, p []byte, first, last time.Time) (int, error) {
+	if tw, ok := w.(timedWriter); ok {
+		return tw.WriteTimed(p, first, last)
+	}
+	return w.Write(p)
+}
+
+// newIndexWriter returns a writer which records each write to the time index of the file.
+// wrap is applied to the file like RotateWriter. If w is not a file, the time index is not written.
+func newIndexWriter(w io.WriteCloser, wrap func(io.WriteCloser) io.WriteCloser, mode os.FileMode, now func() time.Time) io.WriteCloser {
+	f := fileOf(w)
+	if f == nil {
+		if wrap != nil {
+			return wrap(w)
+		}
+		return w
+	}
+	i := &indexWriter{now: now}
+	i.file = &countWriter{WriteCloser: w}
+	i.w = i.file
+	if wrap != nil {
+		i.w = wrap(i.w)
+	}
+	// The file is opened in append mode. Frames are written after the current end.
+	stat, err := f.Stat()
+	if err == nil {
+		i.file.n.Store(stat.Size())
+		flag := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+		if stat.Size() == 0 {
+			// The index of the old file renamed by external tools (e.g. logrotate) is stale.
+			flag |= os.O_TRUNC
+		}
+		i.index, err = os.OpenFile(f.Name()+IndexExt, flag, mode)
+	}
+	i.err = err
+	return i
+}
+
+// indexWriter writes IndexRecord of each frame to the index file.
+// Each write to the underlying writer must be a frame.
+type indexWriter struct {
+	w     io.WriteCloser
+	file  *countWriter
+	index *os.File
+	now   func() time.Time
+	buf   []byte
+	err   error
+}
+
+func (i *indexWriter) Write(p []byte) (int, error) {
+	return i.WriteTimed(p, time.Time{}, time.Time{})
+}
+
+func (i *indexWriter) WriteTimed(p []byte, first, last time.Time) (int, error) {
+	if i.err != nil {
+		return 0, i.err
+	}
+	if first.IsZero() || last.IsZero() {
+		now := i.now()
+		first, last = now, now
+	}
+	offset := i.file.n.Load()
+	n, err := i.w.Write(p)
+	if err != nil {
+		i.err = err
+		return n, err
+	}
+	r := IndexRecord{
+		Offset: offset,
+		Size:   i.file.n.Load() - offset,
+		Length: int64(n),
+		First:  first,
+		Last:   last,
+	}
+	i.buf = r.appendBinary(i.buf[:0])
+	_, i.err = i.index.Write(i.buf)
+	return n, i.err
+}
+
+func (i *indexWriter) Close() error {
+	err := i.w.Close()
+	if i.index != nil {
+		if cerr := i.index.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// fileOf returns the file written by w, or nil if w does not write to a file.
+func fileOf(w io.WriteCloser) *os.File {
+	for {
+		switch v := w.(type) {
+		case *os.File:
+			return v
+		case *countWriter:
+			w = v.WriteCloser
+		default:
+			return nil
+		}
+	}
+}
+
+var errNoIndex = errors.New("time index not found")
+
+// ReadRange returns a reader of frames containing data written in [from, to].
+// Frames are selected by the time index, and only the selected frames are decompressed.
+// Zero from or to means unbounded.
+// Since a frame may contain data written outside the range, the reader may return extra lines around the range.
+func ReadRange(filePath string, from, to time.Time) (io.ReadCloser, error) {
+	records, err := ReadIndex(filePath)
+	if os.IsNotExist(err) {
+		return nil, errNoIndex
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var selected []IndexRecord
+	for _, r := range records {
+		// Frames removed by Repair are ignored.
+		if r.overlaps(from, to) && r.Offset+r.Size <= stat.Size() {
+			selected = append(selected, r)
+		}
+	}
+	return &rangeReader{
+		f:       f,
+		a:       readerAlgorithm(filePath),
+		records: selected,
+	}, nil
+}
+
+// rangeReader decompresses the selected frames in order.
+type rangeReader struct {
+	f          *os.File
+	a          Algorithm
+	records    []IndexRecord
+	compressed []byte
+	buf        bytes.Buffer
+	err        error
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if len(r.records) == 0 {
+			return 0, io.EOF
+		}
+		r.err = r.readFrame(r.records[0])
+		r.records = r.records[1:]
+	}
+	return r.buf.Read(p)
+}
+
+func (r *rangeReader) readFrame(rec IndexRecord) error {
+	r.buf.Reset()
+	r.compressed = append(r.compressed[:0], make([]byte, rec.Size)...)
+	if _, err := r.f.ReadAt(r.compressed, rec.Offset); err != nil {
+		return &FrameError{Offset: rec.Offset, Err: err}
+	}
+	if err := r.a.Decompress(r.compressed, &r.buf); err != nil {
+		return &FrameError{Offset: rec.Offset, Err: err}
+	}
+	return nil
+}
+
+func (r *rangeReader) Close() error {
+	return r.f.Close()
+}
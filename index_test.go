@@ -0,0 +1,148 @@
+package logwriter
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRange(t *testing.T) {
+	for _, inFlight := range []int{0, 2} {
+		t.Run(fmt.Sprint("MaxInFlight=", inFlight), func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "test.log.zst")
+			f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+			if !assert.NoError(t, err) {
+				return
+			}
+			base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			now := base
+			nowFn := func() time.Time { return now }
+			wrap := func(w io.WriteCloser) io.WriteCloser {
+				return NewCompressedWriter(w, &ZstdAlgorithm{})
+			}
+			b := newBuffer(1<<20, time.Hour, newIndexWriter(f, wrap, 0666, nowFn), nowFn)
+			b.MaxInFlight = inFlight
+
+			// Write a frame per minute.
+			lines := make([]string, 5)
+			for m := range lines {
+				for s := 0; s < 3; s++ {
+					now = base.Add(time.Duration(m)*time.Minute + time.Duration(s)*10*time.Second)
+					line := fmt.Sprintf("%s\n", now.Format(time.TimeOnly))
+					lines[m] += line
+					_, err = b.Write([]byte(line))
+					assert.NoError(t, err)
+				}
+				b.flush(true)
+			}
+			assert.NoError(t, b.Close())
+
+			records, err := ReadIndex(filePath)
+			assert.NoError(t, err)
+			if !assert.Len(t, records, 5) {
+				return
+			}
+			var offset int64
+			for m, r := range records {
+				assert.Equal(t, offset, r.Offset)
+				assert.Equal(t, int64(len(lines[m])), r.Length)
+				assert.True(t, base.Add(time.Duration(m)*time.Minute).Equal(r.First))
+				assert.True(t, base.Add(time.Duration(m)*time.Minute+20*time.Second).Equal(r.Last))
+				offset += r.Size
+			}
+			stat, err := os.Stat(filePath)
+			assert.NoError(t, err)
+			assert.Equal(t, stat.Size(), offset)
+
+			readRange := func(from, to time.Time) string {
+				r, err := ReadRange(filePath, from, to)
+				if !assert.NoError(t, err) {
+					return ""
+				}
+				defer r.Close()
+				data, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				return string(data)
+			}
+			assert.Equal(t, lines[1]+lines[2], readRange(base.Add(70*time.Second), base.Add(130*time.Second)))
+			assert.Equal(t, lines[3]+lines[4], readRange(base.Add(3*time.Minute), time.Time{}))
+			assert.Equal(t, strings.Join(lines, ""), readRange(time.Time{}, time.Time{}))
+			assert.Equal(t, "", readRange(base.Add(time.Hour), time.Time{}))
+		})
+	}
+}
+
+func TestReadIndex_partialRecord(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.TimeIndex = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, "foo\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	// A crashed process left a partial record.
+	idx, err := os.OpenFile(filePath+IndexExt, os.O_WRONLY|os.O_APPEND, 0)
+	if assert.NoError(t, err) {
+		_, err = idx.Write([]byte{1, 2, 3})
+		assert.NoError(t, err)
+		assert.NoError(t, idx.Close())
+	}
+	records, err := ReadIndex(filePath)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, IndexRecord{Offset: 0, Size: 4, Length: 4, First: records[0].First, Last: records[0].Last}, records[0])
+	}
+
+	r, err := ReadRange(filePath, time.Now().Add(-time.Hour), time.Time{})
+	if assert.NoError(t, err) {
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "foo\n", string(data))
+		r.Close()
+	}
+
+	_, err = ReadRange(filepath.Join(t.TempDir(), "not-indexed.log"), time.Time{}, time.Time{})
+	assert.Equal(t, errNoIndex, err)
+
+	opt.StreamCompression = true
+	opt.FileOrDir = filePath + ".zst"
+	_, err = Open(opt)
+	assert.Error(t, err)
+}
+
+func TestReadRange_dictionary(t *testing.T) {
+	dict, err := BuildDictionary(toBytes(sampleFrames(100)), 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	opt := DefaultOpenOption
+	opt.FileOrDir = filepath.Join(t.TempDir(), "test.log.zst")
+	opt.Compression.Dictionary = dict
+	opt.TimeIndex = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, sampleFrames(1)[0])
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	r, err := ReadRange(opt.FileOrDir, time.Time{}, time.Time{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleFrames(1)[0], string(data))
+}
@@ -0,0 +1,32 @@
+package logwriter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// currentLinkPath returns the path to the symlink pointing at the active file.
+func currentLinkPath(opt OpenOption) string {
+	return filepath.Join(opt.FileOrDir, fmt.Sprintf("%s.current.log%s", opt.Prefix, opt.Suffix))
+}
+
+// updateCurrentLink atomically replaces the symlink to point at filePath.
+// The link target is relative so that the directory can be moved.
+func updateCurrentLink(opt OpenOption, filePath string) error {
+	link := currentLinkPath(opt)
+	target, err := filepath.Rel(opt.FileOrDir, filePath)
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.tmp-%d", link, os.Getpid())
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
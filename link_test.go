@@ -0,0 +1,84 @@
+package logwriter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestOpen_CurrentLink(t *testing.T) {
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "test"
+	opt.BufferSize = 10
+	opt.MaxFileSize = 20
+	opt.UncompressedFileSize = true
+	opt.CurrentLink = true
+	link := filepath.Join(dir, "test.current.log.zst")
+	newest := func() string {
+		files, err := listLogFiles(dir, opt.Prefix, opt.Suffix)
+		assert.NoError(t, err)
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].before(files[j])
+		})
+		if len(files) == 0 {
+			return ""
+		}
+		return filepath.Base(files[len(files)-1].path)
+	}
+
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	target, err := os.Readlink(link)
+	assert.NoError(t, err)
+	assert.Equal(t, newest(), target)
+
+	line := "0123456789\n"
+	for i := 0; i < 10; i++ {
+		_, err = w.Write([]byte(line))
+		assert.NoError(t, err)
+		target, err = os.Readlink(link)
+		assert.NoError(t, err)
+		assert.Equal(t, newest(), target)
+	}
+	assert.NoError(t, w.Close())
+
+	// The link is left pointing at the last file.
+	target, err = os.Readlink(link)
+	assert.NoError(t, err)
+	assert.Equal(t, newest(), target)
+	files, err := listLogFiles(dir, opt.Prefix, opt.Suffix)
+	assert.NoError(t, err)
+	assert.Len(t, files, 5)
+	assert.Equal(t, "0123456789\n0123456789\n", readLogFile(t, link))
+}
+
+func TestOpen_CurrentLink_withoutRotation(t *testing.T) {
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "test"
+	opt.CurrentLink = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = w.Write([]byte("foo\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	link := filepath.Join(dir, "test.current.log.zst")
+	target, err := os.Readlink(link)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^test\..+-\d+\.log\.zst$`, target)
+	assert.Equal(t, "foo\n", readLogFile(t, link))
+	// No temporary links are left.
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,71 @@
+package logwriter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LockMode specifies how Open locks the file against other processes writing to the same path.
+type LockMode int
+
+const (
+	// NoLock does not lock the file.
+	NoLock LockMode = iota
+	// LockFailFast makes Open return ErrLocked if another process holds the lock.
+	LockFailFast
+	// LockWait blocks Open until another process releases the lock.
+	LockWait
+	// LockFallback writes to the sibling file whose name contains the pid if another process holds the lock.
+	// For example, "app.log.zst" is replaced with "app-1234.log.zst".
+	LockFallback
+)
+
+// ErrLocked is returned by Open when another process holds the lock of the file.
+var ErrLocked = errors.New("file is locked by another process")
+
+// openLocked opens filePath and locks it according to mode.
+// The lock is held until the file is closed.
+// If another process holds the lock and mode is LockFallback, the sibling file is opened instead.
+// os.O_TRUNC in flag is applied after locking not to truncate the file written by another process.
+func openLocked(filePath string, flag int, perm os.FileMode, mode LockMode) (*os.File, error) {
+	if mode == NoLock {
+		return os.OpenFile(filePath, flag, perm)
+	}
+	f, err := os.OpenFile(filePath, flag&^os.O_TRUNC, perm)
+	if err != nil {
+		return nil, err
+	}
+	err = lockFile(f, mode == LockWait)
+	if errors.Is(err, ErrLocked) && mode == LockFallback {
+		f.Close()
+		return openLocked(siblingPath(filePath), flag, perm, LockFailFast)
+	}
+	if err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "lock", Path: filePath, Err: err}
+	}
+	if flag&os.O_TRUNC != 0 {
+		if err = f.Truncate(0); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// siblingPath returns the path to the file used by LockFallback.
+// The pid is inserted before ".log" like generateFilePath, or before the extensions if the name has no ".log".
+func siblingPath(filePath string) string {
+	dir, name := filepath.Split(filePath)
+	i := strings.LastIndex(name, ".log")
+	if i < 0 {
+		i = strings.IndexByte(name, '.')
+	}
+	if i <= 0 {
+		i = len(name)
+	}
+	return dir + name[:i] + "-" + strconv.Itoa(os.Getpid()) + name[i:]
+}
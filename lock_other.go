@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package logwriter
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not supported on this platform.
+func lockFile(f *os.File, wait bool) error {
+	return errors.New("file locking is not supported on this platform")
+}
@@ -0,0 +1,152 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package logwriter
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_siblingPath(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	assert.Equal(t, "/var/log/app-"+pid+".log.zst", siblingPath("/var/log/app.log.zst"))
+	assert.Equal(t, "/var/log/app.v2-"+pid+".log", siblingPath("/var/log/app.v2.log"))
+	assert.Equal(t, "app-"+pid+".gz", siblingPath("app.gz"))
+	assert.Equal(t, "app-"+pid, siblingPath("app"))
+	assert.Equal(t, ".app-"+pid, siblingPath(".app"))
+}
+
+func TestOpen_LockMode(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "test.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	open := func(mode LockMode) (io.WriteCloser, error) {
+		opt.LockMode = mode
+		return Open(opt)
+	}
+
+	first, err := open(LockFailFast)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(first, "foo\n")
+	assert.NoError(t, err)
+
+	// LockFailFast
+	_, err = open(LockFailFast)
+	assert.True(t, errors.Is(err, ErrLocked), err)
+
+	// LockFallback
+	fallback, err := open(LockFallback)
+	if assert.NoError(t, err) {
+		_, err = io.WriteString(fallback, "bar\n")
+		assert.NoError(t, err)
+		assert.NoError(t, fallback.Close())
+		assert.Equal(t, "bar\n", readLogFile(t, siblingPath(filePath)))
+	}
+
+	// LockWait
+	done := make(chan error, 1)
+	go func() {
+		w, err := open(LockWait)
+		if err == nil {
+			_, err = io.WriteString(w, "baz\n")
+			if err == nil {
+				err = w.Close()
+			}
+		}
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		t.Fatalf("LockWait returned before the lock was released: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.NoError(t, first.Close())
+	select {
+	case err = <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	assert.Equal(t, "foo\nbaz\n", readLogFile(t, filePath))
+}
+
+func TestOpen_LockMode_repair(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.LockMode = LockFailFast
+	opt.FlushInterval = time.Hour
+	first, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(first, "foo\n")
+	assert.NoError(t, err)
+	// Simulate the frame being written.
+	assert.NoError(t, first.(Reopener).Reopen())
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte{0x28, 0xb5, 0x2f, 0xfd})
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	stat, err := os.Stat(filePath)
+	assert.NoError(t, err)
+
+	// The file locked by another writer is not repaired.
+	opt.RepairOnOpen = true
+	_, err = Open(opt)
+	assert.True(t, errors.Is(err, ErrLocked), err)
+	after, err := os.Stat(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, stat.Size(), after.Size())
+	assert.NoError(t, first.Close())
+}
+
+func TestOpen_LockMode_trunc(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.LockMode = LockFailFast
+	first, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(first, "foo\n")
+	assert.NoError(t, err)
+	assert.NoError(t, first.(Reopener).Reopen())
+	stat, err := os.Stat(filePath)
+	assert.NoError(t, err)
+
+	// The locked file is not truncated.
+	opt.Flag |= os.O_TRUNC
+	for _, mode := range []LockMode{LockFailFast, LockFallback} {
+		opt.LockMode = mode
+		w, err := Open(opt)
+		if err == nil {
+			assert.NoError(t, w.Close())
+		}
+		after, err := os.Stat(filePath)
+		assert.NoError(t, err)
+		assert.Equal(t, stat.Size(), after.Size(), mode)
+	}
+	assert.NoError(t, first.Close())
+
+	// The file is truncated after locking.
+	opt.LockMode = LockFailFast
+	w, err := Open(opt)
+	if assert.NoError(t, err) {
+		_, err = io.WriteString(w, "bar\n")
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	}
+	assert.Equal(t, "bar\n", readLogFile(t, filePath))
+}
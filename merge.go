@@ -0,0 +1,229 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeLayouts are formats of timestamps written by the log package with log.LstdFlags,
+// optionally with log.Lmicroseconds.
+var defaultTimeLayouts = []string{
+	"2006/01/02 15:04:05.000000",
+	"2006/01/02 15:04:05",
+}
+
+// MergeOption specifies how Merge parses log files.
+type MergeOption struct {
+	// TimeLayout is the layout of the timestamp at the beginning of each line (e.g. time.RFC3339).
+	// If empty, formats of the log package (log.LstdFlags with or without log.Lmicroseconds) are used.
+	TimeLayout string
+	// Location is used for timestamps without time zone.
+	// If nil, time.Local is used like the log package without log.LUTC.
+	Location *time.Location
+	// If TagPid is true, each line is prefixed with "[pid] ".
+	// The pid is taken from the file name generated by Open. If the name has no pid, the base name is used instead.
+	TagPid bool
+}
+
+// Merge reads log files and writes their lines to w in chronological order.
+// Lines without a timestamp (e.g. stack traces) are kept after the preceding line.
+// Lines with the same timestamp are written in the order of files.
+// Each file must be sorted by time, as written by a single process.
+func Merge(w io.Writer, files []string, opt MergeOption) error {
+	layouts := defaultTimeLayouts
+	if opt.TimeLayout != "" {
+		layouts = []string{opt.TimeLayout}
+	}
+	loc := opt.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	h := &mergeHeap{}
+	defer func() {
+		for _, s := range h.sources {
+			s.closer.Close()
+		}
+	}()
+	for i, file := range files {
+		r, err := OpenReader(file)
+		if err != nil {
+			return err
+		}
+		s := &mergeSource{
+			index:   i,
+			r:       bufio.NewReader(r),
+			closer:  r,
+			layouts: layouts,
+			loc:     loc,
+		}
+		if opt.TagPid {
+			s.tag = []byte("[" + fileTag(file) + "] ")
+		}
+		if err = s.next(); err != nil {
+			r.Close()
+			return err
+		}
+		if s.record != nil {
+			h.sources = append(h.sources, s)
+		} else {
+			r.Close()
+		}
+	}
+	heap.Init(h)
+
+	bw := bufio.NewWriter(w)
+	for 0 < h.Len() {
+		s := h.sources[0]
+		if err := s.writeRecord(bw); err != nil {
+			return err
+		}
+		if err := s.next(); err != nil {
+			return err
+		}
+		if s.record == nil {
+			s.closer.Close()
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return bw.Flush()
+}
+
+// fileTag returns the pid in the file name generated by Open, or the base name if the name has no pid.
+func fileTag(file string) string {
+	name := filepath.Base(file)
+	if i := strings.LastIndex(name, ".log"); 0 <= i {
+		if j := strings.LastIndexByte(name[:i], '-'); 0 <= j {
+			if _, err := strconv.ParseUint(name[j+1:i], 10, 0); err == nil {
+				return name[j+1 : i]
+			}
+		}
+	}
+	return name
+}
+
+// mergeSource reads records from a log file.
+// A record is a line with a timestamp followed by lines without timestamps.
+type mergeSource struct {
+	index   int
+	r       *bufio.Reader
+	closer  io.Closer
+	layouts []string
+	loc     *time.Location
+	tag     []byte
+	// record is the lines of the current record. It is nil at the end of the file.
+	record [][]byte
+	time   time.Time
+	// pending is the line with a timestamp read ahead.
+	pending     []byte
+	pendingTime time.Time
+	eof         bool
+}
+
+// next reads the next record.
+func (s *mergeSource) next() error {
+	s.record = nil
+	if s.pending != nil {
+		s.record = append(s.record, s.pending)
+		s.time = s.pendingTime
+		s.pending = nil
+	}
+	for !s.eof {
+		line, err := s.r.ReadBytes('\n')
+		if err == io.EOF {
+			s.eof = true
+			if len(line) == 0 {
+				break
+			}
+			line = append(line, '\n')
+		} else if err != nil {
+			return err
+		}
+		t, ok := parseLineTime(line, s.layouts, s.loc)
+		if ok && s.record != nil {
+			// The line starts the next record.
+			s.pending = line
+			s.pendingTime = t
+			break
+		}
+		if s.record == nil {
+			// Lines without timestamps at the beginning of the file are written first.
+			s.time = t
+		}
+		s.record = append(s.record, line)
+	}
+	return nil
+}
+
+func (s *mergeSource) writeRecord(w *bufio.Writer) error {
+	for _, line := range s.record {
+		w.Write(s.tag)
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLineTime parses the timestamp at the beginning of the line.
+// The timestamp consists of as many space-separated fields as the layout.
+func parseLineTime(line []byte, layouts []string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range layouts {
+		fields := strings.Count(layout, " ") + 1
+		end := 0
+		for i := 0; i < fields; i++ {
+			j := bytes.IndexAny(line[end:], " \n")
+			if j < 0 {
+				end = len(line)
+				break
+			}
+			end += j
+			if i < fields-1 {
+				end++
+			}
+		}
+		if t, err := time.ParseInLocation(layout, string(line[:end]), loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// mergeHeap orders sources by the time of the current record, and then the order of files.
+type mergeHeap struct {
+	sources []*mergeSource
+}
+
+func (h *mergeHeap) Len() int {
+	return len(h.sources)
+}
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.sources[i], h.sources[j]
+	if a.time.Equal(b.time) {
+		return a.index < b.index
+	}
+	return a.time.Before(b.time)
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+	h.sources[i], h.sources[j] = h.sources[j], h.sources[i]
+}
+
+func (h *mergeHeap) Push(x any) {
+	h.sources = append(h.sources, x.(*mergeSource))
+}
+
+func (h *mergeHeap) Pop() any {
+	s := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return s
+}
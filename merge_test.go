@@ -0,0 +1,69 @@
+package logwriter
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "app.2000-01-01T00:00:00Z-100.log.zst")
+	b := filepath.Join(dir, "app.2000-01-01T00:00:00Z-200.log.gz")
+	assert.NoError(t, os.WriteFile(a, compressFrames(t, &ZstdAlgorithm{}, []string{
+		"2000/01/01 00:00:01 a1\n",
+		"2000/01/01 00:00:03 a2\npanic: error\n\tstack trace\n",
+		"2000/01/01 00:00:05 a3",
+	}), 0666))
+	assert.NoError(t, os.WriteFile(b, compressFrames(t, &GzipAlgorithm{}, []string{
+		"header without timestamp\n",
+		"2000/01/01 00:00:02 b1\n2000/01/01 00:00:03 b2\n",
+		"2000/01/01 00:00:04.500000 b3\n",
+	}), 0666))
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, Merge(&buf, []string{a, b}, MergeOption{Location: time.UTC}))
+	assert.Equal(t, "header without timestamp\n"+
+		"2000/01/01 00:00:01 a1\n"+
+		"2000/01/01 00:00:02 b1\n"+
+		"2000/01/01 00:00:03 a2\npanic: error\n\tstack trace\n"+
+		"2000/01/01 00:00:03 b2\n"+
+		"2000/01/01 00:00:04.500000 b3\n"+
+		"2000/01/01 00:00:05 a3\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, Merge(&buf, []string{b, a}, MergeOption{TagPid: true}))
+	assert.Equal(t, "[200] header without timestamp\n"+
+		"[100] 2000/01/01 00:00:01 a1\n"+
+		"[200] 2000/01/01 00:00:02 b1\n"+
+		"[200] 2000/01/01 00:00:03 b2\n"+
+		"[100] 2000/01/01 00:00:03 a2\n[100] panic: error\n[100] \tstack trace\n"+
+		"[200] 2000/01/01 00:00:04.500000 b3\n"+
+		"[100] 2000/01/01 00:00:05 a3\n", buf.String())
+
+	assert.Error(t, Merge(&buf, []string{filepath.Join(dir, "not-found.log")}, MergeOption{}))
+}
+
+func TestMerge_TimeLayout(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	assert.NoError(t, os.WriteFile(a, []byte("2000-01-01T09:00:00+09:00 a1\n2000-01-01T09:00:02+09:00 a2\n"), 0666))
+	assert.NoError(t, os.WriteFile(b, []byte("2000-01-01T00:00:01Z b1\n"), 0666))
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, Merge(&buf, []string{a, b}, MergeOption{TimeLayout: time.RFC3339, TagPid: true}))
+	assert.Equal(t, "[a.log] 2000-01-01T09:00:00+09:00 a1\n"+
+		"[b.log] 2000-01-01T00:00:01Z b1\n"+
+		"[a.log] 2000-01-01T09:00:02+09:00 a2\n", buf.String())
+}
+
+func Test_fileTag(t *testing.T) {
+	assert.Equal(t, "1234", fileTag("/var/log/app.2000-01-01T00:00:00.123Z-1234.log.zst"))
+	assert.Equal(t, "1234", fileTag("app.2000-01-01T00:00:00+09:00-1234.log"))
+	assert.Equal(t, "app.log", fileTag("/var/log/app.log"))
+	assert.Equal(t, "app-x.log.gz", fileTag("app-x.log.gz"))
+}
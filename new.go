@@ -1,13 +1,13 @@
 package logwriter
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
 	"time"
 )
 
@@ -26,8 +26,28 @@ type OpenOption struct {
 	// Supported extensions list:
 	//	".zst"
 	//	".gz"
+	//	".s2" (S2)
+	//	".sz" (Snappy)
+	//	".zz" (zlib)
+	//	".deflate" (raw deflate)
 	//	"" (without compression)
+	// Other extensions can be added by RegisterAlgorithm.
 	Suffix string
+	// FilenameTemplate specifies the path of generated files relative to the directory, followed by Suffix.
+	// If empty, "<Prefix>.<time>-<pid>.log<Suffix>" is used.
+	//
+	// Supported placeholders:
+	//	{prefix} Prefix
+	//	{hostname} host name
+	//	{pid} process ID
+	//	{seq} sequence number to make the name unique
+	//	{time:LAYOUT} time formatted with the layout of the time package (e.g. {time:2006-01-02T15})
+	// The template may contain directories (e.g. "{time:2006/01/02}/{prefix}.log"). Missing directories are created.
+	// Files are created exclusively. If the name already exists, the smallest {seq} which makes the name unique is used.
+	// If the template has no {seq}, ".<seq>" is inserted before the extension instead.
+	// This option cannot be used with MaxFiles, MaxAge and MaxTotalBytes.
+	// This option only affect if FileOrDir points to a directory.
+	FilenameTemplate string
 	// Flag for open a file.
 	Flag int
 	// Default file mode.
@@ -38,6 +58,109 @@ type OpenOption struct {
 	// FlushInterval specifies the interval to flush the buffer.
 	// If FlushInterval is not a positive value, buffering is disabled.
 	FlushInterval time.Duration
+	// MaxInFlightFlushes specifies the number of buffers being compressed and written in the background.
+	// If MaxInFlightFlushes is positive, new writes continue into a fresh buffer while the full buffer is flushed.
+	// Note that it requires buffer space of (MaxInFlightFlushes + 1) * BufferSize at most.
+	// If MaxInFlightFlushes is not a positive value, the buffer is flushed in the caller's goroutine.
+	// This option only affect if buffering is enabled.
+	MaxInFlightFlushes int
+	// RecordDelimiter enables record-aware buffering.
+	// If RecordDelimiter is not empty (e.g. "\n"), compressed frames are cut only at the end of records,
+	// so every frame decodes to whole records and can be processed independently.
+	// This option only affect if buffering is enabled.
+	RecordDelimiter string
+	// MaxHold specifies how long a partial record is held in the buffer.
+	// If MaxHold is not a positive value, a partial record is held until it completes, it exceeds BufferSize,
+	// or the writer is closed.
+	// This option only affect if RecordDelimiter is not empty.
+	MaxHold time.Duration
+	// CompressionWorkers specifies the number of goroutines compressing buffers in parallel.
+	// Flushing the buffer returns after the data is handed to a worker, and frames are written to the file in order.
+	// If CompressionWorkers is less than 2, buffers are compressed in the goroutine flushing the buffer.
+	CompressionWorkers int
+	// Compression specifies the compression level and encoder options.
+	// Open returns an error if the options are invalid for the algorithm selected by the suffix.
+	Compression CompressionOption
+	// If StreamCompression is true, one compression stream is kept across buffer flushes and is flushed at every buffer flush.
+	// It improves the compression ratio of small flushes, but readers may not read the data until the frame is completed.
+	// This option is not supported by S2 and Snappy, and cannot be used with CompressionWorkers.
+	StreamCompression bool
+	// MaxFrameSize specifies the limit of uncompressed bytes in a frame.
+	// A crash loses at most one incomplete frame.
+	// If MaxFrameSize is not a positive value, DefaultMaxFrameSize is used.
+	// This option only affect if StreamCompression is true.
+	MaxFrameSize int64
+	// If Seekable is true, a seek table is written at the end of the file on Close.
+	// It allows readers to access any uncompressed offset without decompressing the whole file. See OpenSeekableReader.
+	// This option is only supported by zstd, and cannot be used with StreamCompression or CompressionWorkers.
+	Seekable bool
+	// If TimeIndex is true, the position and the time range of each frame are recorded to the index file
+	// whose name is the log file name followed by IndexExt. See ReadRange.
+	// This option cannot be used with StreamCompression or CompressionWorkers.
+	TimeIndex bool
+	// MaxFileSize specifies the size limit of a file in bytes.
+	// When the limit is reached, the current file is closed and a new file is created.
+	// If MaxFileSize is not a positive value, size-based rotation is disabled.
+	// With CompressionWorkers, frames being compressed in the background are not counted yet,
+	// so a file may exceed the limit by up to 2 * CompressionWorkers frames unless UncompressedFileSize is true.
+	// This option only affect if FileOrDir points to a directory.
+	MaxFileSize int64
+	// If UncompressedFileSize is true, MaxFileSize is compared with the size of data before compression.
+	UncompressedFileSize bool
+	// RotateSchedule specifies boundaries of time-based rotation, e.g. HourlyRotation or DailyRotation.
+	// When the time passes a boundary, a new file is created on the next flush.
+	// If RotateSchedule.Interval is not a positive value, time-based rotation is disabled.
+	// This option only affect if FileOrDir points to a directory.
+	RotateSchedule RotateSchedule
+	// MaxFiles specifies the number of log files to keep in the directory.
+	// Old files exceeding the limit are removed on open and after each rotation.
+	// If MaxFiles is not a positive value, files are not removed by count.
+	// This option only affect if FileOrDir points to a directory.
+	MaxFiles int
+	// MaxAge specifies how long log files are kept.
+	// The age is calculated from the time embedded in the file name.
+	// If MaxAge is not a positive value, files are not removed by age.
+	// This option only affect if FileOrDir points to a directory.
+	MaxAge time.Duration
+	// MaxTotalBytes specifies the total size of log files to keep in the directory.
+	// If MaxTotalBytes is not a positive value, files are not removed by total size.
+	// This option only affect if FileOrDir points to a directory.
+	MaxTotalBytes int64
+	// If RepairOnOpen is true, a damaged final frame in the existing file is truncated before appending.
+	// See Repair for details.
+	// This option only affect if FileOrDir points to a file.
+	RepairOnOpen bool
+	// LockMode specifies how the file is locked against other processes writing to the same path.
+	// The lock is held until the writer is closed. The file reopened by Reopen or WatchFile is also locked,
+	// but LockWait does not wait on reopening.
+	// Locking is supported only on platforms providing flock (e.g. Linux).
+	// This option only affect if FileOrDir points to a file.
+	LockMode LockMode
+	// AsyncQueueSize specifies the size of the queue in bytes for asynchronous writing.
+	// If AsyncQueueSize is positive, Write copies data to the queue and returns without waiting for compression and disk I/O.
+	// If AsyncQueueSize is not a positive value, asynchronous writing is disabled.
+	AsyncQueueSize int
+	// OverflowPolicy specifies the behavior when the queue for asynchronous writing is full.
+	// This option only affect if AsyncQueueSize is positive.
+	OverflowPolicy OverflowPolicy
+	// If ReopenOnSIGHUP is true, Setup reopens the file whenever the process receives SIGHUP.
+	// Send SIGHUP after an external tool (e.g. logrotate) renamed the file. See Reopener.
+	// This option only affect Setup.
+	ReopenOnSIGHUP bool
+	// If WatchFile is true, the file is checked at every FlushInterval, and is reopened when it was removed,
+	// renamed or replaced by other process.
+	// Data written to the removed file since the last check is lost.
+	// This option only affect if FlushInterval is positive.
+	WatchFile bool
+	// OnFileEvent is called when the file is reopened by WatchFile.
+	// If nil, events are written to stderr.
+	OnFileEvent func(FileEvent)
+	// If CurrentLink is true, the symlink "<Prefix>.current.log<Suffix>" in the directory points at the file
+	// currently being written. It is atomically replaced whenever a new file is opened.
+	// Open fails if the symlink cannot be created, but failures on rotation are ignored.
+	// On Close, the symlink is left pointing at the last file.
+	// This option only affect if FileOrDir points to a directory.
+	CurrentLink bool
 }
 
 var DefaultOpenOption = OpenOption{
@@ -58,9 +181,14 @@ func Setup(option OpenOption) (TearDown, error) {
 		return nil, err
 	}
 
+	stop := func() {}
+	if option.ReopenOnSIGHUP {
+		stop = reopenOnSignal(w)
+	}
 	old := log.Writer()
 	log.SetOutput(w)
 	return func() error {
+		stop()
 		log.SetOutput(old)
 		return w.Close()
 	}, nil
@@ -96,43 +224,277 @@ func openSlow(opt OpenOption) (io.WriteCloser, error) {
 		return nil, err
 	}
 	if err == nil && stat.IsDir() {
-		dir := opt.FileOrDir
-		filename := fmt.Sprintf(
-			"%s.%s-%d.log%s",
-			opt.Prefix,
-			time.Now().Format(time.RFC3339Nano), os.Getpid(),
-			opt.Suffix,
-		)
-		filePath = path.Join(dir, filename)
+		// Generated files are new. They are neither repaired nor locked.
+		opt.RepairOnOpen = false
+		opt.LockMode = NoLock
+		t, err := parseFileNameTemplate(opt)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil && retentionEnabled(opt) {
+			return nil, errors.New("FilenameTemplate cannot be used with MaxFiles, MaxAge or MaxTotalBytes")
+		}
+		if 0 < opt.MaxFileSize || opt.RotateSchedule.Enabled() {
+			return openRotateLogger(opt, t)
+		}
+		f, filePath, err := createLogFile(opt, t, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+		w, err := openSuitableLogger(filePath, opt)
+		if err != nil {
+			return nil, err
+		}
+		if opt.CurrentLink {
+			if err = updateCurrentLink(opt, filePath); err != nil {
+				w.Close()
+				return nil, err
+			}
+		}
+		if retentionEnabled(opt) {
+			newPruner(opt, time.Now).Trigger(filePath)
+		}
+		return w, nil
 	}
 	// Ignore os.ErrNotExist.
 
 	return openSuitableLogger(filePath, opt)
 }
 
+// fileEventHandler returns the handler of FileEvent specified by opt.
+func fileEventHandler(opt OpenOption) func(FileEvent) {
+	if opt.OnFileEvent != nil {
+		return opt.OnFileEvent
+	}
+	return printFileEvent
+}
+
+// generateFilePath returns a new file path in the directory opt.FileOrDir.
+func generateFilePath(opt OpenOption, now time.Time) string {
+	filename := fmt.Sprintf(
+		"%s.%s-%d.log%s",
+		opt.Prefix,
+		now.Format(time.RFC3339Nano), os.Getpid(),
+		opt.Suffix,
+	)
+	return path.Join(opt.FileOrDir, filename)
+}
+
+// createLogFile creates a new file in the directory opt.FileOrDir.
+// If t is nil, the name is generated by generateFilePath.
+func createLogFile(opt OpenOption, t *fileNameTemplate, now time.Time) (*os.File, string, error) {
+	if t != nil {
+		f, filePath, err := t.create(opt.FileOrDir, opt.Suffix, now, opt.Flag, opt.Mode)
+		if err == nil && len(opt.Compression.Dictionary) != 0 {
+			// Readers look for the dictionary next to the file, which may be in a subdirectory.
+			if err = saveDictionary(filepath.Dir(filePath), opt.Compression.Dictionary); err != nil {
+				f.Close()
+				os.Remove(filePath)
+				return nil, "", err
+			}
+		}
+		return f, filePath, err
+	}
+	filePath := generateFilePath(opt, now)
+	f, err := os.OpenFile(filePath, opt.Flag, opt.Mode)
+	return f, filePath, err
+}
+
 func openSuitableLogger(filePath string, opt OpenOption) (w io.WriteCloser, err error) {
-	w, err = os.OpenFile(filePath, opt.Flag, opt.Mode)
+	// Select compression algorithm
+	wrap, err := compressor(filePath, filepath.Dir(filePath), opt)
+	if err != nil {
+		return nil, err
+	}
+	repair := opt.RepairOnOpen
+	lockMode := opt.LockMode
+	open := func() (io.WriteCloser, error) {
+		f, err := openLocked(filePath, opt.Flag, opt.Mode, lockMode)
+		if err != nil {
+			return nil, err
+		}
+		if lockMode == LockWait {
+			// Reopen and WatchFile must not block writes. They fail with ErrLocked instead of waiting.
+			lockMode = LockFailFast
+		}
+		// Keep writing to the sibling file after LockFallback.
+		filePath = f.Name()
+		if repair {
+			// The file may be damaged by crash of the previous process.
+			// It is repaired after locking not to truncate the frame being written by another process.
+			repair = false
+			if _, err = Repair(filePath); err != nil {
+				f.Close()
+				return nil, err
+			}
+		}
+		return f, nil
+	}
+	// RotateWriter without limits writes to the same path. It reopens the path on Reopen.
+	rotateOpt := RotateOption{
+		Watch:       opt.WatchFile,
+		OnFileEvent: fileEventHandler(opt),
+	}
+	w, err = NewRotateWriter(rotateOpt, open, wrap)
 	if err != nil {
 		return nil, err
 	}
+	return addBuffer(w, opt), nil
+}
 
-	// Select compression algorithm
-	if strings.HasSuffix(filePath, ".gz") {
-		w = NewCompressedWriter(w, &GzipAlgorithm{})
-	} else if strings.HasSuffix(filePath, ".zst") {
-		w = NewCompressedWriter(w, &ZstdAlgorithm{})
+func openRotateLogger(opt OpenOption, t *fileNameTemplate) (w io.WriteCloser, err error) {
+	// All files have the same suffix.
+	wrap, err := compressor(opt.Suffix, opt.FileOrDir, opt)
+	if err != nil {
+		return nil, err
+	}
+	var p *pruner
+	if retentionEnabled(opt) {
+		p = newPruner(opt, time.Now)
 	}
+	first := true
+	open := func() (io.WriteCloser, error) {
+		f, filePath, err := createLogFile(opt, t, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if opt.CurrentLink {
+			// The symlink is best-effort after the first file.
+			if err = updateCurrentLink(opt, filePath); err != nil && first {
+				f.Close()
+				return nil, err
+			}
+		}
+		first = false
+		if p != nil {
+			// Remove old files in the background after the new file was created.
+			p.Trigger(filePath)
+		}
+		return f, nil
+	}
+	rotateOpt := RotateOption{
+		MaxSize:      opt.MaxFileSize,
+		Uncompressed: opt.UncompressedFileSize,
+		Schedule:     opt.RotateSchedule,
+		Watch:        opt.WatchFile,
+		OnFileEvent:  fileEventHandler(opt),
+	}
+	w, err = NewRotateWriter(rotateOpt, open, wrap)
+	if err != nil {
+		return nil, err
+	}
+	return addBuffer(w, opt), nil
+}
+
+// compressor returns a function which wraps a file with the compression algorithm suitable for filePath.
+// It returns nil if the file should not be compressed nor indexed.
+// The dictionary is saved in dir so that readers can find it.
+func compressor(filePath, dir string, opt OpenOption) (func(io.WriteCloser) io.WriteCloser, error) {
+	wrap, err := compressionWrapper(filePath, dir, opt)
+	if err != nil || !opt.TimeIndex {
+		return wrap, err
+	}
+	if opt.StreamCompression || 1 < opt.CompressionWorkers {
+		return nil, errors.New("TimeIndex cannot be used with StreamCompression or CompressionWorkers")
+	}
+	return func(w io.WriteCloser) io.WriteCloser {
+		return newIndexWriter(w, wrap, opt.Mode, time.Now)
+	}, nil
+}
+
+// compressionWrapper returns a function which wraps a file with the compression algorithm.
+// It returns nil if the file should not be compressed.
+func compressionWrapper(filePath, dir string, opt OpenOption) (func(io.WriteCloser) io.WriteCloser, error) {
+	a, err := configuredAlgorithm(filePath, opt.Compression)
+	if a == nil || err != nil {
+		return nil, err
+	}
+	if len(opt.Compression.Dictionary) != 0 {
+		if err = saveDictionary(dir, opt.Compression.Dictionary); err != nil {
+			return nil, err
+		}
+	}
+	if opt.Seekable {
+		if opt.StreamCompression || 1 < opt.CompressionWorkers {
+			return nil, errors.New("Seekable cannot be used with StreamCompression or CompressionWorkers")
+		}
+		z, ok := a.(*ZstdAlgorithm)
+		if !ok {
+			return nil, fmt.Errorf("seekable format is not supported for %s", filePath)
+		}
+		return func(w io.WriteCloser) io.WriteCloser {
+			return NewSeekableWriter(w, z)
+		}, nil
+	}
+	if opt.StreamCompression {
+		if 1 < opt.CompressionWorkers {
+			return nil, errors.New("StreamCompression and CompressionWorkers are exclusive")
+		}
+		sa, ok := a.(StreamAlgorithm)
+		if !ok {
+			return nil, fmt.Errorf("stream compression is not supported for %s", filePath)
+		}
+		return func(w io.WriteCloser) io.WriteCloser {
+			return NewStreamCompressedWriter(w, sa, opt.MaxFrameSize)
+		}, nil
+	}
+	if 1 < opt.CompressionWorkers {
+		newAlgorithm := func() Algorithm {
+			// The options were validated above.
+			a, _ := configuredAlgorithm(filePath, opt.Compression)
+			return a
+		}
+		return func(w io.WriteCloser) io.WriteCloser {
+			return NewParallelCompressedWriter(w, newAlgorithm, opt.CompressionWorkers)
+		}, nil
+	}
+	return func(w io.WriteCloser) io.WriteCloser {
+		return NewCompressedWriter(w, a)
+	}, nil
+}
+
+// configuredAlgorithm returns the algorithm suitable for filePath configured with opt.
+func configuredAlgorithm(filePath string, opt CompressionOption) (Algorithm, error) {
+	a := suitableAlgorithm(filePath)
+	if a == nil || opt.IsZero() {
+		return a, nil
+	}
+	c, ok := a.(ConfigurableAlgorithm)
+	if !ok {
+		return nil, fmt.Errorf("compression options are not supported for %s", filePath)
+	}
+	if err := c.Configure(opt); err != nil {
+		return nil, fmt.Errorf("invalid compression options for %s: %w", filePath, err)
+	}
+	return a, nil
+}
 
+// addBuffer wraps w with Buffer, TickWriter and AsyncWriter.
+func addBuffer(w io.WriteCloser, opt OpenOption) io.WriteCloser {
 	if 0 < opt.BufferSize && 0 < opt.FlushInterval {
 		// Add write buffer to improve compression efficiency.
-		w = NewBuffer(opt.BufferSize, opt.FlushInterval, w)
+		buf := newBuffer(opt.BufferSize, opt.FlushInterval, w, time.Now)
+		buf.MaxInFlight = opt.MaxInFlightFlushes
+		buf.Delimiter = []byte(opt.RecordDelimiter)
+		buf.MaxHold = opt.MaxHold
+		w = buf
 
 		// Add tick writer to flush buffer periodically and protect the thread-unsafe WriteCloser object.
-		w = NewTickWriter(w, opt.FlushInterval)
+		w = newTickWriter(w, opt.FlushInterval, opt.WatchFile)
+	} else if opt.WatchFile && 0 < opt.FlushInterval {
+		// No buffering.
+		// Add tick writer to check the file periodically and protect the thread-unsafe WriteCloser object.
+		w = newTickWriter(w, opt.FlushInterval, true)
 	} else {
 		// No buffering.
 		// Add tick writer to protect the thread-unsafe WriteCloser object.
 		w = NewTickWriter(w, 0)
 	}
-	return
+
+	if 0 < opt.AsyncQueueSize {
+		// Move compression and disk I/O out of the caller's goroutine.
+		w = NewAsyncWriter(w, opt.AsyncQueueSize, opt.OverflowPolicy)
+	}
+	return w
 }
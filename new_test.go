@@ -0,0 +1,109 @@
+package logwriter
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpen_MaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "test"
+	opt.BufferSize = 10
+	opt.MaxFileSize = 20
+	opt.UncompressedFileSize = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	line := "0123456789\n"
+	for i := 0; i < 10; i++ {
+		_, err = w.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "test.*.log.zst"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 5)
+	var total string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		buf := bytes.Buffer{}
+		assert.NoError(t, (&ZstdAlgorithm{}).Decompress(data, &buf))
+		assert.Equal(t, strings.Repeat(line, 2), buf.String())
+		total += buf.String()
+	}
+	assert.Equal(t, strings.Repeat(line, 10), total)
+}
+
+func TestOpen_AsyncQueueSize(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.AsyncQueueSize = 1 << 20
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.IsType(t, &AsyncWriter{}, w)
+	for i := 0; i < 100; i++ {
+		_, err = w.Write([]byte("0123456789\n"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	r, err := OpenReader(filePath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("0123456789\n", 100), string(data))
+}
+
+func TestOpen_Compression(t *testing.T) {
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = filepath.Join(dir, "test.log.zst")
+	opt.Compression = CompressionOption{Level: 19, WindowSize: 1 << 20}
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = w.Write([]byte("0123456789\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	data, err := os.ReadFile(opt.FileOrDir)
+	assert.NoError(t, err)
+	buf := bytes.Buffer{}
+	assert.NoError(t, (&ZstdAlgorithm{}).Decompress(data, &buf))
+	assert.Equal(t, "0123456789\n", buf.String())
+
+	// WindowSize is only supported by zstd.
+	opt.FileOrDir = filepath.Join(dir, "test.log.gz")
+	_, err = Open(opt)
+	assert.Error(t, err)
+
+	// Snappy has no options.
+	opt.FileOrDir = filepath.Join(dir, "test.log.sz")
+	opt.Compression = CompressionOption{Level: 1}
+	_, err = Open(opt)
+	assert.Error(t, err)
+
+	// Rotated files are validated on Open.
+	opt.FileOrDir = dir
+	opt.Suffix = ".gz"
+	opt.MaxFileSize = 100
+	opt.Compression = CompressionOption{Level: 10}
+	_, err = Open(opt)
+	assert.Error(t, err)
+}
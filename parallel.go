@@ -0,0 +1,125 @@
+package logwriter
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// NewParallelCompressedWriter wraps the provided io.WriteCloser with a ParallelCompressedWriter.
+// newAlgorithm is called once per worker because Algorithm is not goroutine-safe.
+// If workers is not a positive value, one worker is used.
+func NewParallelCompressedWriter(w io.WriteCloser, newAlgorithm func() Algorithm, workers int) io.WriteCloser {
+	if workers <= 0 {
+		workers = 1
+	}
+	p := &ParallelCompressedWriter{
+		w:     w,
+		jobs:  make(chan *compressJob, workers),
+		order: make(chan *compressJob, workers),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go p.compressor(newAlgorithm())
+	}
+	go p.writer()
+	return p
+}
+
+// ParallelCompressedWriter compresses data on multiple goroutines like CompressedWriter.
+// Each Write call produces one frame, and frames are written to w strictly in the order of Write calls.
+// The number of frames in flight is limited to twice the number of workers, so Write blocks when
+// compression or w is slower than the caller.
+//
+// If compression or writing to w fails, the error is returned by subsequent Write and Close calls, and
+// frames after the failed frame are not written.
+type ParallelCompressedWriter struct {
+	w io.WriteCloser
+	// jobs sends data to compressors.
+	jobs chan *compressJob
+	// order sends jobs to the writer in the order of Write calls.
+	order  chan *compressJob
+	done   chan struct{}
+	mux    sync.Mutex
+	err    error
+	closed bool
+}
+
+type compressJob struct {
+	data []byte
+	out  bytes.Buffer
+	err  error
+	// compressed is closed after data is compressed.
+	compressed chan struct{}
+}
+
+func (p *ParallelCompressedWriter) Write(data []byte) (int, error) {
+	if p.closed {
+		return 0, os.ErrClosed
+	}
+	if err := p.Err(); err != nil {
+		return 0, err
+	}
+	j := &compressJob{
+		// The caller may reuse data after Write returns.
+		data:       bytes.Clone(data),
+		compressed: make(chan struct{}),
+	}
+	p.order <- j
+	p.jobs <- j
+	return len(data), nil
+}
+
+// Close waits until all frames are written, and closes w.
+func (p *ParallelCompressedWriter) Close() error {
+	if p.closed {
+		return os.ErrClosed
+	}
+	p.closed = true
+	close(p.jobs)
+	close(p.order)
+	<-p.done
+	p.setErr(p.w.Close())
+	return p.Err()
+}
+
+// Err returns the first error occurred in the background.
+func (p *ParallelCompressedWriter) Err() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return p.err
+}
+
+func (p *ParallelCompressedWriter) setErr(err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *ParallelCompressedWriter) compressor(a Algorithm) {
+	for j := range p.jobs {
+		j.err = a.Compress(j.data, &j.out)
+		j.data = nil
+		close(j.compressed)
+	}
+}
+
+func (p *ParallelCompressedWriter) writer() {
+	defer close(p.done)
+	for j := range p.order {
+		<-j.compressed
+		if p.Err() != nil {
+			// Discard frames after the failed frame.
+			continue
+		}
+		if j.err != nil {
+			p.setErr(j.err)
+			continue
+		}
+		_, err := p.w.Write(j.out.Bytes())
+		p.setErr(err)
+	}
+}
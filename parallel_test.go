@@ -0,0 +1,70 @@
+package logwriter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// failAlgorithm fails to compress data containing "fail".
+type failAlgorithm struct {
+	NopAlgorithm
+}
+
+var errCompress = errors.New("compression failed")
+
+func (f *failAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
+	if bytes.Contains(in, []byte("fail")) {
+		return errCompress
+	}
+	return f.NopAlgorithm.Compress(in, out)
+}
+
+func TestParallelCompressedWriter_Write(t *testing.T) {
+	buf := &bytes.Buffer{}
+	newAlgorithm := func() Algorithm {
+		return &ZstdAlgorithm{}
+	}
+	w := NewParallelCompressedWriter(&nopCloserWriter{buf}, newAlgorithm, 4)
+	var expected strings.Builder
+	data := make([]byte, 0, 100)
+	for i := 0; i < 1000; i++ {
+		// Reuse the buffer like Buffer does.
+		data = fmt.Appendf(data[:0], "frame %d\n", i)
+		expected.Write(data)
+		n, err := w.Write(data)
+		assert.NoError(t, err)
+		assert.Equal(t, len(data), n)
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, os.ErrClosed, w.Close())
+
+	r := NewCompressedReader(bytes.NewReader(buf.Bytes()), &ZstdAlgorithm{})
+	decoded, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), string(decoded))
+}
+
+func TestParallelCompressedWriter_Write_error(t *testing.T) {
+	bw := &bufferTestWriter{}
+	w := NewParallelCompressedWriter(bw, func() Algorithm { return &failAlgorithm{} }, 2)
+	_, err := w.Write([]byte("ok"))
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("fail"))
+	assert.NoError(t, err)
+	// Write may return the error if the previous frame has already failed.
+	_, err = w.Write([]byte("discarded"))
+	if err != nil {
+		assert.Equal(t, errCompress, err)
+	}
+	assert.Equal(t, errCompress, w.Close())
+	assert.Equal(t, []interface{}{
+		&bufferWriteAction{Data: "ok"},
+		&bufferCloseAction{},
+	}, bw.Actions)
+}
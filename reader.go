@@ -0,0 +1,163 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FrameError is returned when a frame cannot be decompressed.
+type FrameError struct {
+	// Offset is the position of the damaged frame in the compressed data.
+	Offset int64
+	Err    error
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("damaged frame at offset %d: %s", e.Offset, e.Err)
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// Truncated reports whether the frame is cut off by the end of data.
+// It usually happens when the writer process was killed while writing the frame.
+func (e *FrameError) Truncated() bool {
+	return errors.Is(e.Err, io.ErrUnexpectedEOF)
+}
+
+// NewCompressedReader returns a reader which decompresses data written by CompressedWriter.
+// If a implements FrameAlgorithm, frames are decompressed one by one with bounded memory.
+// Otherwise, all data is read into memory before decompression.
+func NewCompressedReader(r io.Reader, a Algorithm) io.ReadCloser {
+	return newCompressedReader(r, a, nil)
+}
+
+// NewTolerantReader returns a CompressedReader which stops reading at the first damaged frame without error.
+// All complete frames before the damaged frame are returned, and the damage is reported by Damage().
+func NewTolerantReader(r io.Reader, a Algorithm) *CompressedReader {
+	c := newCompressedReader(r, a, nil)
+	c.Tolerant = true
+	return c
+}
+
+// OpenReader opens a log file and returns a reader which decompresses it.
+// The compression algorithm is selected from the file extension in the same way as Open.
+func OpenReader(filePath string) (io.ReadCloser, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedReader(f, readerAlgorithm(filePath), f), nil
+}
+
+// readerAlgorithm selects decompression algorithm from the file extension.
+// Dictionary files in the same directory are used to decompress zstd frames.
+func readerAlgorithm(filePath string) Algorithm {
+	if a := suitableAlgorithm(filePath); a != nil {
+		if z, ok := a.(*ZstdAlgorithm); ok {
+			z.dictDir = filepath.Dir(filePath)
+		}
+		return a
+	}
+	return &NopAlgorithm{}
+}
+
+func newCompressedReader(r io.Reader, a Algorithm, closer io.Closer) *CompressedReader {
+	return &CompressedReader{
+		r:      bufio.NewReader(r),
+		a:      a,
+		closer: closer,
+	}
+}
+
+// CompressedReader decompresses concatenated frames written by CompressedWriter.
+// If a frame is damaged, Read returns *FrameError.
+type CompressedReader struct {
+	// If Tolerant is true, Read returns io.EOF instead of *FrameError.
+	Tolerant bool
+	r        *bufio.Reader
+	a        Algorithm
+	closer   io.Closer
+	buf      bytes.Buffer
+	// offset is the number of compressed bytes consumed from r.
+	offset int64
+	err    error
+	damage *FrameError
+	closed bool
+}
+
+// Offset returns the number of compressed bytes of frames which have been read successfully.
+func (c *CompressedReader) Offset() int64 {
+	return c.offset
+}
+
+// Damage returns the damaged frame found while reading, or nil if no damage was found.
+func (c *CompressedReader) Damage() *FrameError {
+	return c.damage
+}
+
+func (c *CompressedReader) Read(p []byte) (int, error) {
+	if c.closed {
+		return 0, os.ErrClosed
+	}
+	for c.buf.Len() == 0 && c.err == nil {
+		c.readFrame()
+	}
+	if 0 < c.buf.Len() {
+		return c.buf.Read(p)
+	}
+	return 0, c.err
+}
+
+func (c *CompressedReader) Close() error {
+	if c.closed {
+		return os.ErrClosed
+	}
+	c.closed = true
+	if c.closer != nil {
+		return c.closer.Close()
+	}
+	return nil
+}
+
+func (c *CompressedReader) readFrame() {
+	c.buf.Reset()
+	var n int64
+	if fa, ok := c.a.(FrameAlgorithm); ok {
+		n, c.err = fa.ReadFrame(c.r, &c.buf)
+	} else {
+		n, c.err = c.readAll()
+	}
+	if c.err != nil {
+		// Drop the incomplete frame.
+		c.buf.Reset()
+		if c.err != io.EOF {
+			c.damage = &FrameError{Offset: c.offset, Err: c.err}
+			c.err = c.damage
+			if c.Tolerant {
+				c.err = io.EOF
+			}
+		}
+		return
+	}
+	c.offset += n
+}
+
+// readAll decompresses all remaining data at once.
+// It is used when the algorithm does not implement FrameAlgorithm.
+func (c *CompressedReader) readAll() (int64, error) {
+	in, err := io.ReadAll(c.r)
+	if err != nil {
+		return 0, err
+	}
+	if len(in) == 0 {
+		return 0, io.EOF
+	}
+	return int64(len(in)), c.a.Decompress(in, &c.buf)
+}
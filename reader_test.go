@@ -0,0 +1,174 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// plainAlgorithm hides FrameAlgorithm implementation of NopAlgorithm.
+type plainAlgorithm struct {
+	NopAlgorithm
+}
+
+func compressFrames(t *testing.T, a Algorithm, frames []string) []byte {
+	buf := &bytes.Buffer{}
+	cw := NewCompressedWriter(&nopCloserWriter{buf}, a)
+	for _, frame := range frames {
+		_, err := cw.Write([]byte(frame))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, cw.Close())
+	return buf.Bytes()
+}
+
+type nopCloserWriter struct {
+	io.Writer
+}
+
+func (n *nopCloserWriter) Close() error {
+	return nil
+}
+
+func TestCompressedReader_Read(t *testing.T) {
+	large := strings.Repeat("large frame\n", nopFrameSize/4)
+	frames := []string{"foo\n", "", "bar\nbaz\n", large}
+	algorithms := map[string]func() Algorithm{
+		"nop":     func() Algorithm { return &NopAlgorithm{} },
+		"plain":   func() Algorithm { return &plainAlgorithm{} },
+		"gzip":    func() Algorithm { return &GzipAlgorithm{} },
+		"zstd":    func() Algorithm { return &ZstdAlgorithm{} },
+		"s2":      func() Algorithm { return &S2Algorithm{} },
+		"snappy":  func() Algorithm { return &SnappyAlgorithm{} },
+		"deflate": func() Algorithm { return &DeflateAlgorithm{} },
+		"zlib":    func() Algorithm { return &ZlibAlgorithm{} },
+	}
+	for name, newAlgorithm := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			compressed := compressFrames(t, newAlgorithm(), frames)
+			r := NewCompressedReader(bytes.NewReader(compressed), newAlgorithm())
+			data, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, strings.Join(frames, ""), string(data))
+			assert.NoError(t, r.Close())
+			_, err = r.Read(nil)
+			assert.Equal(t, os.ErrClosed, err)
+		})
+	}
+}
+
+func TestCompressedReader_Read_skippableFrame(t *testing.T) {
+	compressed := compressFrames(t, &ZstdAlgorithm{}, []string{"foo\n"})
+	compressed = append(compressed, 0x50, 0x2A, 0x4D, 0x18, 3, 0, 0, 0, 'x', 'y', 'z')
+	compressed = append(compressed, compressFrames(t, &ZstdAlgorithm{}, []string{"bar\n"})...)
+
+	data, err := io.ReadAll(NewCompressedReader(bytes.NewReader(compressed), &ZstdAlgorithm{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\nbar\n", string(data))
+}
+
+func TestZstdAlgorithm_ReadFrame_largeSkippableFrame(t *testing.T) {
+	// A damaged header declares a 4GiB skippable frame.
+	compressed := []byte{0x50, 0x2A, 0x4D, 0x18, 0xff, 0xff, 0xff, 0xff, 'x', 'y', 'z'}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := (&ZstdAlgorithm{}).ReadFrame(bufio.NewReader(bytes.NewReader(compressed)), &bytes.Buffer{})
+	runtime.ReadMemStats(&after)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+
+	// The size of a skippable frame includes the discarded payload.
+	n, err := (&ZstdAlgorithm{}).ReadFrame(bufio.NewReader(bytes.NewReader(compressed)), &bytes.Buffer{})
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, int64(11), n)
+	compressed = []byte{0x50, 0x2A, 0x4D, 0x18, 3, 0, 0, 0, 'x', 'y', 'z'}
+	n, err = (&ZstdAlgorithm{}).ReadFrame(bufio.NewReader(bytes.NewReader(compressed)), &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), n)
+}
+
+// frameAlgorithms returns algorithms which can detect truncated frames.
+func frameAlgorithms() map[string]Algorithm {
+	return map[string]Algorithm{
+		"gzip":    &GzipAlgorithm{},
+		"zstd":    &ZstdAlgorithm{},
+		"s2":      &S2Algorithm{},
+		"snappy":  &SnappyAlgorithm{},
+		"deflate": &DeflateAlgorithm{},
+		"zlib":    &ZlibAlgorithm{},
+	}
+}
+
+func TestCompressedReader_Read_truncated(t *testing.T) {
+	for name, a := range frameAlgorithms() {
+		t.Run(name, func(t *testing.T) {
+			compressed := compressFrames(t, a, []string{"foo\n", "bar\n"})
+			data, err := io.ReadAll(NewCompressedReader(bytes.NewReader(compressed[:len(compressed)-3]), a))
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			assert.Equal(t, "foo\n", string(data))
+
+			var frameErr *FrameError
+			if assert.ErrorAs(t, err, &frameErr) {
+				assert.Equal(t, int64(len(compressFrames(t, a, []string{"foo\n"}))), frameErr.Offset)
+				assert.True(t, frameErr.Truncated())
+			}
+		})
+	}
+}
+
+func TestOpenReader(t *testing.T) {
+	dir := t.TempDir()
+	for _, suffix := range suffixes() {
+		t.Run(fmt.Sprintf("suffix=%q", suffix), func(t *testing.T) {
+			opt := DefaultOpenOption
+			opt.FileOrDir = filepath.Join(dir, "test.log"+suffix)
+			w, err := Open(opt)
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = io.WriteString(w, "hello\n")
+			assert.NoError(t, err)
+			assert.NoError(t, w.Close())
+
+			r, err := OpenReader(opt.FileOrDir)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer r.Close()
+			data, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, "hello\n", string(data))
+		})
+	}
+}
+
+func TestNewTolerantReader(t *testing.T) {
+	for name, a := range frameAlgorithms() {
+		t.Run(name, func(t *testing.T) {
+			compressed := compressFrames(t, a, []string{"foo\n", "bar\n"})
+			good := len(compressFrames(t, a, []string{"foo\n"}))
+
+			r := NewTolerantReader(bytes.NewReader(compressed[:len(compressed)-3]), a)
+			data, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, "foo\n", string(data))
+			assert.Equal(t, int64(good), r.Offset())
+			if assert.NotNil(t, r.Damage()) {
+				assert.Equal(t, int64(good), r.Damage().Offset)
+			}
+
+			r = NewTolerantReader(bytes.NewReader(compressed), a)
+			data, err = io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, "foo\nbar\n", string(data))
+			assert.Nil(t, r.Damage())
+		})
+	}
+}
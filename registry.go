@@ -0,0 +1,67 @@
+package logwriter
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+var registry = struct {
+	mux       sync.RWMutex
+	factories map[string]func() Algorithm
+}{
+	factories: map[string]func() Algorithm{
+		".gz":      func() Algorithm { return &GzipAlgorithm{} },
+		".zst":     func() Algorithm { return &ZstdAlgorithm{} },
+		".s2":      func() Algorithm { return &S2Algorithm{} },
+		".sz":      func() Algorithm { return &SnappyAlgorithm{} },
+		".zz":      func() Algorithm { return &ZlibAlgorithm{} },
+		".deflate": func() Algorithm { return &DeflateAlgorithm{} },
+	},
+}
+
+// RegisterAlgorithm registers the compression algorithm for files with the extension (e.g. ".zst").
+// The registered algorithm is used by Open, OpenReader and other functions selecting algorithm from file names.
+// If the extension is already registered, it is overridden.
+// factory must return a new Algorithm each time because Algorithm is not goroutine-safe.
+func RegisterAlgorithm(ext string, factory func() Algorithm) {
+	if ext == "" {
+		panic("logwriter: RegisterAlgorithm extension is empty")
+	}
+	if factory == nil {
+		panic("logwriter: RegisterAlgorithm factory is nil")
+	}
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+	registry.factories[ext] = factory
+}
+
+// suitableAlgorithm selects compression algorithm from the file extension.
+// If multiple extensions match, the longest one is used.
+// It returns nil if the file should not be compressed.
+func suitableAlgorithm(filePath string) Algorithm {
+	registry.mux.RLock()
+	defer registry.mux.RUnlock()
+	var matched string
+	for ext := range registry.factories {
+		if strings.HasSuffix(filePath, ext) && len(matched) < len(ext) {
+			matched = ext
+		}
+	}
+	if matched == "" {
+		return nil
+	}
+	return registry.factories[matched]()
+}
+
+// suffixes returns a list of supported file extensions including "" (without compression).
+func suffixes() []string {
+	registry.mux.RLock()
+	defer registry.mux.RUnlock()
+	exts := []string{""}
+	for ext := range registry.factories {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
@@ -0,0 +1,72 @@
+package logwriter
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// xorAlgorithm is a toy algorithm for testing.
+type xorAlgorithm struct{}
+
+func (x *xorAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
+	for _, b := range in {
+		out.WriteByte(b ^ 0xff)
+	}
+	return nil
+}
+
+func (x *xorAlgorithm) Decompress(in []byte, out *bytes.Buffer) error {
+	return x.Compress(in, out)
+}
+
+func unregisterAlgorithm(ext string) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+	delete(registry.factories, ext)
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	RegisterAlgorithm(".xor", func() Algorithm { return &xorAlgorithm{} })
+	defer unregisterAlgorithm(".xor")
+
+	filePath := filepath.Join(t.TempDir(), "test.log.xor")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, "hello\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	raw, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{^byte('h'), ^byte('e'), ^byte('l'), ^byte('l'), ^byte('o'), ^byte('\n')}, raw)
+
+	r, err := OpenReader(filePath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+	assert.Contains(t, suffixes(), ".xor")
+}
+
+func Test_suitableAlgorithm(t *testing.T) {
+	RegisterAlgorithm(".xor.gz", func() Algorithm { return &xorAlgorithm{} })
+	defer unregisterAlgorithm(".xor.gz")
+
+	assert.IsType(t, &GzipAlgorithm{}, suitableAlgorithm("test.log.gz"))
+	assert.IsType(t, &ZstdAlgorithm{}, suitableAlgorithm("test.log.zst"))
+	assert.IsType(t, &xorAlgorithm{}, suitableAlgorithm("test.log.xor.gz"))
+	assert.Nil(t, suitableAlgorithm("test.log"))
+	assert.Panics(t, func() { RegisterAlgorithm("", func() Algorithm { return &xorAlgorithm{} }) })
+	assert.Panics(t, func() { RegisterAlgorithm(".xor", nil) })
+}
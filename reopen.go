@@ -0,0 +1,48 @@
+package logwriter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Reopener is implemented by writers returned by Open.
+type Reopener interface {
+	// Reopen flushes buffered data, finishes the current compressed frame, and reopens the file.
+	// It is used with external log rotation tools (e.g. logrotate) which rename the file being written.
+	// The same path is reopened. If FileOrDir points to a directory and MaxFileSize or RotateSchedule is enabled,
+	// a new file is created instead.
+	Reopen() error
+}
+
+// reopen calls w.Reopen if w supports it. Otherwise it does nothing.
+func reopen(w io.Writer) error {
+	if r, ok := w.(Reopener); ok {
+		return r.Reopen()
+	}
+	return nil
+}
+
+// reopenOnSignal reopens w whenever the process receives SIGHUP.
+// Errors are written to stderr because w may not be usable.
+// The returned function stops the handler.
+func reopenOnSignal(w io.Writer) (stop func()) {
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		defer close(done)
+		for range sig {
+			if err := reopen(w); err != nil {
+				fmt.Fprintf(os.Stderr, "logwriter: failed to reopen: %s\n", err)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sig)
+		close(sig)
+		<-done
+	}
+}
@@ -0,0 +1,83 @@
+package logwriter
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// readLogFile decompresses the whole file.
+func readLogFile(t *testing.T, filePath string) string {
+	r, err := OpenReader(filePath)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func TestOpen_Reopen(t *testing.T) {
+	for _, queueSize := range []int{0, 1 << 20} {
+		t.Run(fmt.Sprint("AsyncQueueSize=", queueSize), func(t *testing.T) {
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "test.log.zst")
+			rotated := filepath.Join(dir, "test.1.log.zst")
+			opt := DefaultOpenOption
+			opt.FileOrDir = filePath
+			opt.AsyncQueueSize = queueSize
+			w, err := Open(opt)
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = io.WriteString(w, "foo\n")
+			assert.NoError(t, err)
+
+			// Rotated by an external tool.
+			assert.NoError(t, os.Rename(filePath, rotated))
+			assert.NoError(t, w.(Reopener).Reopen())
+			_, err = io.WriteString(w, "bar\n")
+			assert.NoError(t, err)
+			assert.NoError(t, w.Close())
+
+			assert.Equal(t, "foo\n", readLogFile(t, rotated))
+			assert.Equal(t, "bar\n", readLogFile(t, filePath))
+			assert.Equal(t, os.ErrClosed, w.(Reopener).Reopen())
+		})
+	}
+}
+
+func TestSetup_ReopenOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "test.log.zst")
+	rotated := filepath.Join(dir, "test.1.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.ReopenOnSIGHUP = true
+	tearDown, err := Setup(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	log.Print("foo")
+	assert.NoError(t, os.Rename(filePath, rotated))
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, p.Signal(syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filePath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	log.Print("bar")
+	assert.NoError(t, tearDown())
+
+	assert.Contains(t, readLogFile(t, rotated), "foo\n")
+	assert.Contains(t, readLogFile(t, filePath), "bar\n")
+}
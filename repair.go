@@ -0,0 +1,42 @@
+package logwriter
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ErrNotRepairable is returned by Repair when a damaged frame is followed by other data.
+// Such files are not truncated because it may discard valid frames.
+var ErrNotRepairable = errors.New("damaged frame is not at the end of file")
+
+// Check reads the whole log file and returns the damaged frame.
+// It returns nil if the file is not damaged.
+func Check(filePath string) (*FrameError, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := NewTolerantReader(f, readerAlgorithm(filePath))
+	if _, err = io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	return r.Damage(), nil
+}
+
+// Repair truncates the log file at the beginning of the damaged final frame.
+// After repairing, new frames can be appended to the file safely.
+// It returns the damage found in the file, or nil if the file is not damaged.
+// If the damaged frame is not at the end of file, it returns ErrNotRepairable without modifying the file.
+func Repair(filePath string) (*FrameError, error) {
+	damage, err := Check(filePath)
+	if err != nil || damage == nil {
+		return damage, err
+	}
+	if !damage.Truncated() {
+		return damage, ErrNotRepairable
+	}
+	return damage, os.Truncate(filePath, damage.Offset)
+}
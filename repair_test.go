@@ -0,0 +1,80 @@
+package logwriter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	for _, suffix := range []string{".gz", ".zst"} {
+		t.Run(suffix, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "test.log"+suffix)
+			a := suitableAlgorithm(filePath)
+			good := compressFrames(t, a, []string{"foo\n"})
+			damaged := compressFrames(t, a, []string{"foo\n", "bar\n"})
+			damaged = damaged[:len(damaged)-3]
+			assert.NoError(t, os.WriteFile(filePath, damaged, 0666))
+
+			damage, err := Check(filePath)
+			assert.NoError(t, err)
+			if assert.NotNil(t, damage) {
+				assert.Equal(t, int64(len(good)), damage.Offset)
+			}
+
+			damage, err = Repair(filePath)
+			assert.NoError(t, err)
+			assert.NotNil(t, damage)
+			data, err := os.ReadFile(filePath)
+			assert.NoError(t, err)
+			assert.Equal(t, good, data)
+
+			damage, err = Repair(filePath)
+			assert.NoError(t, err)
+			assert.Nil(t, damage)
+		})
+	}
+}
+
+func TestRepair_notRepairable(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	data := compressFrames(t, &ZstdAlgorithm{}, []string{"foo\n"})
+	data = append(data, "garbage"...)
+	data = append(data, compressFrames(t, &ZstdAlgorithm{}, []string{"bar\n"})...)
+	assert.NoError(t, os.WriteFile(filePath, data, 0666))
+
+	damage, err := Repair(filePath)
+	assert.Equal(t, ErrNotRepairable, err)
+	assert.NotNil(t, damage)
+	stat, err := os.Stat(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), stat.Size())
+}
+
+func TestOpen_RepairOnOpen(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	damaged := compressFrames(t, &ZstdAlgorithm{}, []string{"foo\n", "bar\n"})
+	assert.NoError(t, os.WriteFile(filePath, damaged[:len(damaged)-3], 0666))
+
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.RepairOnOpen = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, "baz\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	r, err := OpenReader(filePath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\nbaz\n", string(data))
+}
@@ -0,0 +1,210 @@
+package logwriter
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func newPruner(opt OpenOption, now func() time.Time) *pruner {
+	return &pruner{
+		dir:           opt.FileOrDir,
+		prefix:        opt.Prefix,
+		suffix:        opt.Suffix,
+		maxFiles:      opt.MaxFiles,
+		maxAge:        opt.MaxAge,
+		maxTotalBytes: opt.MaxTotalBytes,
+		now:           now,
+	}
+}
+
+// pruner removes old log files in the background.
+// It only removes files whose names are generated by generateFilePath.
+// The newest file of each running process is never removed, since other processes may share the directory.
+type pruner struct {
+	dir           string
+	prefix        string
+	suffix        string
+	maxFiles      int
+	maxAge        time.Duration
+	maxTotalBytes int64
+	now           func() time.Time
+
+	mux sync.Mutex
+	// active is the path to the file currently being written. It is never removed.
+	active  string
+	running bool
+	pending bool
+	wg      sync.WaitGroup
+}
+
+// logFile is a log file found in the directory.
+type logFile struct {
+	path    string
+	created time.Time
+	pid     int
+	size    int64
+}
+
+// before reports whether f was created before other.
+func (f logFile) before(other logFile) bool {
+	if f.created.Equal(other.created) {
+		return f.path < other.path
+	}
+	return f.created.Before(other.created)
+}
+
+func retentionEnabled(opt OpenOption) bool {
+	return 0 < opt.MaxFiles || 0 < opt.MaxAge || 0 < opt.MaxTotalBytes
+}
+
+// Trigger starts removing old files in the background without blocking the caller.
+// If the pruner is already running, it runs once more after the current run.
+func (p *pruner) Trigger(active string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.active = active
+	if p.running {
+		p.pending = true
+		return
+	}
+	p.running = true
+	p.wg.Add(1)
+	go p.worker()
+}
+
+// Wait waits for the background run to finish.
+func (p *pruner) Wait() {
+	p.wg.Wait()
+}
+
+func (p *pruner) worker() {
+	defer p.wg.Done()
+	for {
+		p.mux.Lock()
+		active := p.active
+		p.pending = false
+		p.mux.Unlock()
+
+		// Retention is a best-effort operation. Errors are ignored and retried on the next run.
+		p.prune(active)
+
+		p.mux.Lock()
+		if !p.pending {
+			p.running = false
+			p.mux.Unlock()
+			return
+		}
+		p.mux.Unlock()
+	}
+}
+
+func (p *pruner) prune(active string) {
+	files, err := listLogFiles(p.dir, p.prefix, p.suffix)
+	if err != nil {
+		return
+	}
+	// Newest first.
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].created.After(files[j].created)
+	})
+
+	now := p.now()
+	var total int64
+	// The newest file of each process may be written by other processes sharing the directory.
+	newest := map[int]bool{}
+	for i, f := range files {
+		total += f.size
+		remove := (0 < p.maxFiles && p.maxFiles <= i) ||
+			(0 < p.maxAge && p.maxAge < now.Sub(f.created)) ||
+			(0 < p.maxTotalBytes && p.maxTotalBytes < total)
+		isNewest := !newest[f.pid]
+		newest[f.pid] = true
+		if !remove || f.path == active || (isNewest && processAlive(f.pid)) {
+			continue
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+			// Remove the time index of the file if exists.
+			os.Remove(f.path + IndexExt)
+		}
+	}
+}
+
+// listLogFiles returns log files whose names are generated by generateFilePath.
+func listLogFiles(dir, prefix, suffix string) ([]logFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []logFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		created, pid, ok := parseFileName(entry.Name(), prefix, suffix)
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// The file may be removed by other process.
+			continue
+		}
+		files = append(files, logFile{
+			path:    path.Join(dir, entry.Name()),
+			created: created,
+			pid:     pid,
+			size:    info.Size(),
+		})
+	}
+	return files, nil
+}
+
+// parseFileName parses a file name generated by generateFilePath.
+// It returns the time when the file was created, the pid of the process, and whether the name matches the pattern.
+func parseFileName(name, prefix, suffix string) (created time.Time, pid int, ok bool) {
+	rest, ok := strings.CutPrefix(name, prefix+".")
+	if !ok {
+		return
+	}
+	rest, ok = strings.CutSuffix(rest, ".log"+suffix)
+	if !ok {
+		return
+	}
+	// Time may contain '-'. Pid is after the last '-'.
+	i := strings.LastIndexByte(rest, '-')
+	if i < 0 {
+		return time.Time{}, 0, false
+	}
+	n, err := strconv.ParseUint(rest[i+1:], 10, 31)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	created, err = time.Parse(time.RFC3339Nano, rest[:i])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return created, int(n), true
+}
+
+// processAlive reports whether the process is running.
+// It returns false if the platform cannot check it.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	// EPERM means the process is owned by another user.
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,139 @@
+package logwriter
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFileName(t *testing.T) {
+	created := time.Date(2000, 1, 2, 3, 4, 5, 6, time.FixedZone("", -7*60*60))
+	opt := OpenOption{Prefix: "app", Suffix: ".zst"}
+	name := filepath.Base(generateFilePath(opt, created))
+
+	parsed, pid, ok := parseFileName(name, "app", ".zst")
+	assert.True(t, ok)
+	assert.True(t, created.Equal(parsed))
+	assert.Equal(t, os.Getpid(), pid)
+
+	for _, name := range []string{
+		"app.log.zst",
+		"app.2000-01-02T03:04:05Z.log.zst",
+		"app.2000-01-02T03:04:05Z-x.log.zst",
+		"app.not-a-time-123.log.zst",
+		"app.2000-01-02T03:04:05Z-123.log",
+		"app.2000-01-02T03:04:05Z-123.log.gz",
+		"other.2000-01-02T03:04:05Z-123.log.zst",
+		"app.2000-01-02T03:04:05Z-123.log.zst.bak",
+	} {
+		_, _, ok := parseFileName(name, "app", ".zst")
+		assert.False(t, ok, name)
+	}
+}
+
+func TestPruner_Trigger(t *testing.T) {
+	now := time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)
+	cases := []struct {
+		name string
+		opt  OpenOption
+		// Names of files which should be kept. Files are created every hour, and file "0" is the newest.
+		kept []string
+	}{
+		{
+			name: "max files",
+			opt:  OpenOption{MaxFiles: 2},
+			kept: []string{"0", "1", "active"},
+		}, {
+			name: "max age",
+			opt:  OpenOption{MaxAge: 150 * time.Minute},
+			kept: []string{"0", "1", "2", "active"},
+		}, {
+			name: "max total bytes",
+			opt:  OpenOption{MaxTotalBytes: 35},
+			kept: []string{"0", "1", "2", "active"},
+		}, {
+			name: "never remove active file",
+			opt:  OpenOption{MaxFiles: 1},
+			kept: []string{"0", "active"},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			opt := testCase.opt
+			opt.FileOrDir = dir
+			opt.Prefix = "app"
+			opt.Suffix = ".zst"
+
+			names := map[string]string{}
+			create := func(label string, created time.Time) string {
+				p := generateFilePath(opt, created)
+				assert.NoError(t, os.WriteFile(p, []byte("0123456789"), 0666))
+				names[filepath.Base(p)] = label
+				return p
+			}
+			for i := 0; i < 5; i++ {
+				create(string(rune('0'+i)), now.Add(-time.Duration(i)*time.Hour))
+			}
+			// The active file is the oldest to ensure it is not removed by any policy.
+			active := create("active", now.Add(-24*time.Hour))
+			// Files not generated by generateFilePath.
+			for _, name := range []string{"app.log.zst", "other.2000-01-01T00:00:00Z-1.log.zst", "app.current.log.zst"} {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0666))
+				names[name] = name
+			}
+
+			p := newPruner(opt, func() time.Time { return now })
+			p.Trigger(active)
+			p.Wait()
+
+			entries, err := os.ReadDir(dir)
+			assert.NoError(t, err)
+			var kept []string
+			for _, entry := range entries {
+				label := names[entry.Name()]
+				if !strings.Contains(label, ".") {
+					kept = append(kept, label)
+				}
+			}
+			sort.Strings(kept)
+			assert.Equal(t, testCase.kept, kept)
+			assert.Len(t, entries, len(kept)+3)
+		})
+	}
+}
+
+func TestPruner_otherProcesses(t *testing.T) {
+	now := time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)
+	dir := t.TempDir()
+	opt := OpenOption{FileOrDir: dir, Prefix: "app", Suffix: ".zst", MaxFiles: 1}
+	create := func(created time.Time, pid int) string {
+		name := fmt.Sprintf("app.%s-%d.log.zst", created.Format(time.RFC3339Nano), pid)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("0123456789"), 0666))
+		return name
+	}
+	active := create(now, os.Getpid())
+	create(now.Add(-time.Hour), os.Getpid())
+	// The newest file of the running process is being written.
+	running := create(now.Add(-2*time.Hour), os.Getppid())
+	create(now.Add(-3*time.Hour), os.Getppid())
+	// The process has exited.
+	create(now.Add(-4*time.Hour), 1<<30)
+
+	p := newPruner(opt, func() time.Time { return now })
+	p.Trigger(filepath.Join(dir, active))
+	p.Wait()
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var kept []string
+	for _, entry := range entries {
+		kept = append(kept, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{active, running}, kept)
+}
@@ -0,0 +1,221 @@
+package logwriter
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// NewRotateWriter creates a RotateWriter and opens the first file.
+// open is called whenever a new file is required.
+// wrap is applied to each opened file (e.g. NewCompressedWriter). If wrap is nil, data is written to the file as is.
+func NewRotateWriter(opt RotateOption, open func() (io.WriteCloser, error), wrap func(io.WriteCloser) io.WriteCloser) (io.WriteCloser, error) {
+	return newRotateWriter(opt, open, wrap, time.Now)
+}
+
+func newRotateWriter(opt RotateOption, open func() (io.WriteCloser, error), wrap func(io.WriteCloser) io.WriteCloser, now func() time.Time) (io.WriteCloser, error) {
+	r := &RotateWriter{
+		RotateOption: opt,
+		Now:          now,
+		open:         open,
+		wrap:         wrap,
+	}
+	if err := r.openNext(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RotateOption specifies when RotateWriter switches to a new file.
+type RotateOption struct {
+	// MaxSize is the size limit of a file in bytes.
+	// If MaxSize is not a positive value, size-based rotation is disabled.
+	MaxSize int64
+	// If Uncompressed is true, the size of data before wrapping is compared with MaxSize.
+	// Otherwise, the number of bytes written to the file is compared.
+	Uncompressed bool
+	// Schedule specifies boundaries of time-based rotation.
+	Schedule RotateSchedule
+	// If Watch is true, the file is reopened when it was removed, renamed or replaced by other process.
+	// The check runs at the interval of TickWriter wrapping RotateWriter.
+	Watch bool
+	// OnFileEvent is called when the watchdog reopened the file.
+	OnFileEvent func(FileEvent)
+}
+
+// RotateWriter writes data to a file, and switches to a new file when the file size reaches MaxSize or
+// the time passes a boundary of Schedule.
+// Each Write call is passed to the underlying writer as is, so a compressed frame never straddles two files.
+//
+//	var file io.WriteCloser
+//	w = NewRotateWriter(RotateOption{MaxSize: maxSize}, openNewFile, wrap)
+//	w = NewBuffer(size, time.Second, w)
+//	w = NewTickWriter(w, time.Second)
+type RotateWriter struct {
+	RotateOption
+	// Now() is a function returns current time like time.Time().
+	// This function used to inject time from outside.
+	Now  func() time.Time
+	open func() (io.WriteCloser, error)
+	wrap func(io.WriteCloser) io.WriteCloser
+	// w is nil if the current file was closed and the next file is not opened yet.
+	w     io.WriteCloser
+	file  *countWriter
+	plain int64
+	// deadline is the next boundary of Schedule.
+	deadline time.Time
+	err      error
+	closed   bool
+}
+
+func (r *RotateWriter) Write(p []byte) (int, error) {
+	return r.WriteTimed(p, time.Time{}, time.Time{})
+}
+
+// WriteTimed writes p with the time range of the data. The time range is passed to the underlying writer.
+func (r *RotateWriter) WriteTimed(p []byte, first, last time.Time) (int, error) {
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.err != nil || len(p) == 0 {
+		return 0, r.err
+	}
+	if r.w != nil && r.expired() {
+		r.err = r.closeCurrent()
+		if r.err != nil {
+			return 0, r.err
+		}
+	}
+	if r.w == nil {
+		r.err = r.openNext()
+		if r.err != nil {
+			return 0, r.err
+		}
+	}
+
+	var n int
+	n, r.err = writeTimed(r.w, p, first, last)
+	r.plain += int64(n)
+	if r.err == nil && r.needRotate() {
+		// Close the current file immediately. The next file is opened on the next write.
+		r.err = r.closeCurrent()
+	}
+	return n, r.err
+}
+
+func (r *RotateWriter) Close() error {
+	if r.closed {
+		return os.ErrClosed
+	}
+	r.closed = true
+	err := r.closeCurrent()
+	if r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+// Reopen closes the current file and opens the next file.
+func (r *RotateWriter) Reopen() error {
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.closeCurrent()
+	if r.err == nil {
+		r.err = r.openNext()
+	}
+	return r.err
+}
+
+// checkFile reopens the file if it is not at its path.
+func (r *RotateWriter) checkFile() error {
+	if !r.Watch || r.closed || r.err != nil || r.w == nil {
+		return r.err
+	}
+	f := fileOf(r.file)
+	if f == nil {
+		return nil
+	}
+	reason, err := fileMoved(f)
+	if err != nil || reason == "" {
+		// Ignore temporary errors. The next tick checks again.
+		return nil
+	}
+	// Keep writing to the current file until the next file is opened.
+	next, err := r.open()
+	if err != nil {
+		// Ignore errors (e.g. the directory was removed). The next tick retries.
+		return nil
+	}
+	// Errors on closing the moved file are ignored not to stop writing to the next file.
+	r.closeCurrent()
+	r.use(next)
+	if r.OnFileEvent != nil {
+		r.OnFileEvent(FileEvent{Path: f.Name(), Reason: reason, Time: r.Now()})
+	}
+	return nil
+}
+
+func (r *RotateWriter) needRotate() bool {
+	if r.MaxSize <= 0 {
+		return false
+	}
+	size := r.file.n.Load()
+	if r.Uncompressed {
+		size = r.plain
+	}
+	return r.MaxSize <= size
+}
+
+func (r *RotateWriter) expired() bool {
+	return r.Schedule.Enabled() && !r.Now().Before(r.deadline)
+}
+
+func (r *RotateWriter) openNext() error {
+	f, err := r.open()
+	if err != nil {
+		return err
+	}
+	r.use(f)
+	return nil
+}
+
+// use starts writing to f. The current file must be closed.
+func (r *RotateWriter) use(f io.WriteCloser) {
+	r.file = &countWriter{WriteCloser: f}
+	r.w = r.file
+	if r.wrap != nil {
+		r.w = r.wrap(r.w)
+	}
+	r.plain = 0
+	if r.Schedule.Enabled() {
+		r.deadline = r.Schedule.Next(r.Now())
+	}
+}
+
+func (r *RotateWriter) closeCurrent() error {
+	if r.w == nil {
+		return nil
+	}
+	err := r.w.Close()
+	r.w = nil
+	r.file = nil
+	return err
+}
+
+// countWriter counts the number of bytes written to the WriteCloser.
+// n is atomic because ParallelCompressedWriter writes in the background while RotateWriter reads it.
+type countWriter struct {
+	io.WriteCloser
+	n atomic.Int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.WriteCloser.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
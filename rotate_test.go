@@ -0,0 +1,207 @@
+package logwriter
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRotateWriter_Write(t *testing.T) {
+	cases := []struct {
+		name         string
+		maxSize      int64
+		uncompressed bool
+		writes       []string
+		// Actions of each opened file.
+		files [][]interface{}
+	}{
+		{
+			name:   "close without write",
+			writes: []string{},
+			files: [][]interface{}{
+				{&bufferCloseAction{}},
+			},
+		}, {
+			name:   "rotation disabled",
+			writes: []string{"foo", "bar", "baz"},
+			files: [][]interface{}{
+				{
+					&bufferWriteAction{Data: "foo"},
+					&bufferWriteAction{Data: "bar"},
+					&bufferWriteAction{Data: "baz"},
+					&bufferCloseAction{},
+				},
+			},
+		}, {
+			name:    "rotate when size limit reached",
+			maxSize: 6,
+			writes:  []string{"foo", "bar", "baz", "large data"},
+			files: [][]interface{}{
+				{
+					&bufferWriteAction{Data: "foo"},
+					&bufferWriteAction{Data: "bar"},
+					&bufferCloseAction{},
+				}, {
+					&bufferWriteAction{Data: "baz"},
+					&bufferWriteAction{Data: "large data"},
+					&bufferCloseAction{},
+				},
+			},
+		}, {
+			name:    "do not open next file until next write",
+			maxSize: 3,
+			writes:  []string{"foo"},
+			files: [][]interface{}{
+				{
+					&bufferWriteAction{Data: "foo"},
+					&bufferCloseAction{},
+				},
+			},
+		}, {
+			name:         "compare uncompressed size",
+			maxSize:      6,
+			uncompressed: true,
+			writes:       []string{"foo", "bar", "baz"},
+			files: [][]interface{}{
+				{
+					&bufferWriteAction{Data: "foofoo"},
+					&bufferWriteAction{Data: "barbar"},
+					&bufferCloseAction{},
+				}, {
+					&bufferWriteAction{Data: "bazbaz"},
+					&bufferCloseAction{},
+				},
+			},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var files []*bufferTestWriter
+			open := func() (io.WriteCloser, error) {
+				f := &bufferTestWriter{}
+				files = append(files, f)
+				return f, nil
+			}
+			var wrap func(io.WriteCloser) io.WriteCloser
+			if testCase.uncompressed {
+				// Double the size of data to distinguish the uncompressed size from the written size.
+				wrap = func(w io.WriteCloser) io.WriteCloser {
+					return &doubleWriter{w}
+				}
+			}
+			w, err := NewRotateWriter(RotateOption{MaxSize: testCase.maxSize, Uncompressed: testCase.uncompressed}, open, wrap)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, data := range testCase.writes {
+				n, err := w.Write([]byte(data))
+				assert.NoError(t, err)
+				assert.Equal(t, len(data), n)
+			}
+			assert.NoError(t, w.Close())
+			assert.Equal(t, os.ErrClosed, w.Close())
+
+			var actions [][]interface{}
+			for _, f := range files {
+				actions = append(actions, f.Actions)
+			}
+			assert.Equal(t, testCase.files, actions)
+		})
+	}
+}
+
+type doubleWriter struct {
+	io.WriteCloser
+}
+
+func (d *doubleWriter) Write(p []byte) (int, error) {
+	_, err := d.WriteCloser.Write(append(append([]byte{}, p...), p...))
+	return len(p), err
+}
+
+func TestRotateWriter_Schedule(t *testing.T) {
+	currentTime := time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC)
+	elapsed := time.Duration(0)
+	now := func() time.Time {
+		return currentTime.Add(elapsed)
+	}
+	var files []*bufferTestWriter
+	open := func() (io.WriteCloser, error) {
+		f := &bufferTestWriter{}
+		files = append(files, f)
+		return f, nil
+	}
+	w, err := newRotateWriter(RotateOption{Schedule: HourlyRotation}, open, nil, now)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	write := func(data string) {
+		_, err := w.Write([]byte(data))
+		assert.NoError(t, err)
+	}
+	write("03:04\n")
+	elapsed = 55 * time.Minute // 03:59
+	write("03:59\n")
+	elapsed = 56 * time.Minute // 04:00
+	write("04:00\n")
+	elapsed = 3 * time.Hour // 06:04
+	write("06:04\n")
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, []*bufferTestWriter{
+		{Actions: []interface{}{
+			&bufferWriteAction{Data: "03:04\n"},
+			&bufferWriteAction{Data: "03:59\n"},
+			&bufferCloseAction{},
+		}},
+		{Actions: []interface{}{
+			&bufferWriteAction{Data: "04:00\n"},
+			&bufferCloseAction{},
+		}},
+		{Actions: []interface{}{
+			&bufferWriteAction{Data: "06:04\n"},
+			&bufferCloseAction{},
+		}},
+	}, files)
+}
+
+func TestOpen_MaxFileSize_compressionWorkers(t *testing.T) {
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "test"
+	opt.BufferSize = 100
+	opt.CompressionWorkers = 4
+	opt.MaxFileSize = 2000
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// ParallelCompressedWriter counts the file size in the background while RotateWriter reads it.
+	var expected string
+	for i := 0; i < 500; i++ {
+		line := fmt.Sprintf("%d: %x\n", i, sha256.Sum256([]byte(fmt.Sprint(i))))
+		expected += line
+		_, err = io.WriteString(w, line)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	files, err := listLogFiles(dir, opt.Prefix, opt.Suffix)
+	assert.NoError(t, err)
+	assert.Less(t, 1, len(files))
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].before(files[j])
+	})
+	var actual string
+	for _, f := range files {
+		actual += readLogFile(t, f.path)
+	}
+	assert.Equal(t, expected, actual)
+}
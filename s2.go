@@ -0,0 +1,154 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/snappy"
+	"io"
+	"sync"
+)
+
+var _ FrameAlgorithm = &S2Algorithm{}
+var _ ConfigurableAlgorithm = &S2Algorithm{}
+
+// NewS2Algorithm returns S2Algorithm with the writer options, e.g. s2.WriterBestCompression().
+// Invalid options are reported by Compress.
+func NewS2Algorithm(opts ...s2.WriterOption) *S2Algorithm {
+	return &S2Algorithm{opts: opts}
+}
+
+// S2Algorithm compresses data in the S2 stream format.
+// S2 is an extension of Snappy, and much faster than zstd with lower compression ratio.
+type S2Algorithm struct {
+	once sync.Once
+	opts []s2.WriterOption
+	sw   *s2.Writer
+	sr   *s2.Reader
+	// frame is a buffer for ReadFrame.
+	frame []byte
+}
+
+func (s *S2Algorithm) Configure(opt CompressionOption) error {
+	if err := opt.levelOnly(); err != nil {
+		return err
+	}
+	switch opt.Level {
+	case 0, 1:
+	case 2:
+		s.opts = append(s.opts, s2.WriterBetterCompression())
+	case 3:
+		s.opts = append(s.opts, s2.WriterBestCompression())
+	default:
+		return fmt.Errorf("invalid s2 level: %d", opt.Level)
+	}
+	return nil
+}
+
+func (s *S2Algorithm) Compress(in []byte, out *bytes.Buffer) error {
+	s.once.Do(s.init)
+	return compressStream(s.sw, in, out)
+}
+
+func (s *S2Algorithm) Decompress(in []byte, out *bytes.Buffer) error {
+	return decompressFrames(s, in, out)
+}
+
+// ReadFrame reads an S2 stream starting with a stream identifier.
+func (s *S2Algorithm) ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error) {
+	s.once.Do(s.init)
+	var err error
+	s.frame, err = readSnappyFrame(r, s.frame[:0])
+	n := int64(len(s.frame))
+	if err != nil {
+		return n, err
+	}
+	s.sr.Reset(bytes.NewReader(s.frame))
+	_, err = io.Copy(out, s.sr)
+	return n, err
+}
+
+func (s *S2Algorithm) init() {
+	s.sw = s2.NewWriter(Discard, s.writerOptions()...)
+	s.sr = s2.NewReader(nil)
+}
+
+var _ FrameAlgorithm = &SnappyAlgorithm{}
+
+// SnappyAlgorithm compresses data in the Snappy framing format.
+type SnappyAlgorithm struct {
+	once  sync.Once
+	sw    *snappy.Writer
+	sr    *snappy.Reader
+	frame []byte
+}
+
+func (s *SnappyAlgorithm) Compress(in []byte, out *bytes.Buffer) error {
+	s.once.Do(s.init)
+	return compressStream(s.sw, in, out)
+}
+
+func (s *SnappyAlgorithm) Decompress(in []byte, out *bytes.Buffer) error {
+	return decompressFrames(s, in, out)
+}
+
+// ReadFrame reads a Snappy stream starting with a stream identifier.
+func (s *SnappyAlgorithm) ReadFrame(r *bufio.Reader, out *bytes.Buffer) (int64, error) {
+	s.once.Do(s.init)
+	var err error
+	s.frame, err = readSnappyFrame(r, s.frame[:0])
+	n := int64(len(s.frame))
+	if err != nil {
+		return n, err
+	}
+	s.sr.Reset(bytes.NewReader(s.frame))
+	_, err = io.Copy(out, s.sr)
+	return n, err
+}
+
+func (s *S2Algorithm) writerOptions() []s2.WriterOption {
+	return append([]s2.WriterOption{s2.WriterConcurrency(1)}, s.opts...)
+}
+
+func (s *SnappyAlgorithm) init() {
+	s.sw = snappy.NewBufferedWriter(Discard)
+	s.sr = snappy.NewReader(nil)
+}
+
+// snappyStreamIdentifier is the chunk type of the stream identifier.
+const snappyStreamIdentifier = 0xff
+
+// readSnappyFrame reads chunks from a stream identifier to the next stream identifier, and appends them to buf.
+// See https://github.com/google/snappy/blob/main/framing_format.txt for the format.
+func readSnappyFrame(r *bufio.Reader, buf []byte) ([]byte, error) {
+	for {
+		header, err := r.Peek(4)
+		if len(header) == 0 && err == io.EOF {
+			if len(buf) == 0 {
+				return buf, io.EOF
+			}
+			return buf, nil
+		}
+		if 0 < len(header) && header[0] == snappyStreamIdentifier && 0 < len(buf) {
+			// Beginning of the next frame.
+			return buf, nil
+		}
+		if len(header) < 4 {
+			return buf, io.ErrUnexpectedEOF
+		}
+		if len(buf) == 0 && header[0] != snappyStreamIdentifier {
+			return buf, s2.ErrCorrupt
+		}
+
+		size := 4 + (int(header[1]) | int(header[2])<<8 | int(header[3])<<16)
+		start := len(buf)
+		buf = append(buf, make([]byte, size)...)
+		if _, err = io.ReadFull(r, buf[start:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return buf, err
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package logwriter
+
+import (
+	"time"
+)
+
+var (
+	// HourlyRotation switches to a new file at every hour on the hour.
+	HourlyRotation = RotateSchedule{Interval: time.Hour}
+	// DailyRotation switches to a new file at 00:00 UTC.
+	DailyRotation = RotateSchedule{Interval: 24 * time.Hour}
+)
+
+// DailyRotationIn returns a RotateSchedule which switches to a new file at 00:00 in loc.
+func DailyRotationIn(loc *time.Location) RotateSchedule {
+	return RotateSchedule{
+		Interval: 24 * time.Hour,
+		Epoch:    time.Date(2000, 1, 1, 0, 0, 0, 0, loc),
+	}
+}
+
+// RotateSchedule specifies boundaries of time-based rotation.
+// Boundaries are placed at Epoch + n * Interval in the wall clock of Epoch's location,
+// so daily boundaries stay at midnight even across daylight saving time changes.
+type RotateSchedule struct {
+	// Interval between boundaries.
+	// If Interval is not a positive value, time-based rotation is disabled.
+	Interval time.Duration
+	// Epoch is the origin of boundaries.
+	// If Epoch is zero, the Unix epoch is used.
+	Epoch time.Time
+}
+
+// Enabled reports whether time-based rotation is enabled.
+func (s RotateSchedule) Enabled() bool {
+	return 0 < s.Interval
+}
+
+// Next returns the first boundary after t.
+func (s RotateSchedule) Next(t time.Time) time.Time {
+	epoch := s.Epoch
+	if epoch.IsZero() {
+		epoch = time.Unix(0, 0).UTC()
+	}
+	loc := epoch.Location()
+
+	// Calculate in the wall clock time of loc.
+	elapsed := t.Sub(epoch) + zoneOffsetDiff(epoch, t.In(loc))
+	n := elapsed / s.Interval
+	if elapsed < 0 && elapsed%s.Interval != 0 {
+		// Round toward negative infinity.
+		n--
+	}
+	next := epoch.Add((n + 1) * s.Interval)
+	next = next.Add(-zoneOffsetDiff(epoch, next.In(loc)))
+	for !next.After(t) {
+		// The wall clock went back at t.
+		next = next.Add(s.Interval)
+	}
+	return next
+}
+
+// zoneOffsetDiff returns the difference of zone offsets between base and t.
+func zoneOffsetDiff(base, t time.Time) time.Duration {
+	_, baseOffset := base.Zone()
+	_, offset := t.Zone()
+	return time.Duration(offset-baseOffset) * time.Second
+}
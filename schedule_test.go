@@ -0,0 +1,65 @@
+package logwriter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRotateSchedule_Next(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+
+	cases := []struct {
+		name     string
+		schedule RotateSchedule
+		now      time.Time
+		next     time.Time
+	}{
+		{
+			name:     "hourly",
+			schedule: HourlyRotation,
+			now:      time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC),
+			next:     time.Date(2000, 1, 2, 4, 0, 0, 0, time.UTC),
+		}, {
+			name:     "hourly on the boundary",
+			schedule: HourlyRotation,
+			now:      time.Date(2000, 1, 2, 3, 0, 0, 0, time.UTC),
+			next:     time.Date(2000, 1, 2, 4, 0, 0, 0, time.UTC),
+		}, {
+			name:     "daily in UTC",
+			schedule: DailyRotation,
+			now:      time.Date(2000, 1, 2, 3, 4, 5, 6, jst),
+			next:     time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		}, {
+			name:     "daily in local time",
+			schedule: DailyRotationIn(jst),
+			now:      time.Date(2000, 1, 2, 3, 4, 5, 6, jst),
+			next:     time.Date(2000, 1, 3, 0, 0, 0, 0, jst),
+		}, {
+			name:     "daily before the epoch",
+			schedule: DailyRotationIn(jst),
+			now:      time.Date(1999, 1, 2, 3, 4, 5, 6, jst),
+			next:     time.Date(1999, 1, 3, 0, 0, 0, 0, jst),
+		}, {
+			name:     "daily across daylight saving time",
+			schedule: DailyRotationIn(newYork),
+			now:      time.Date(2000, 7, 1, 12, 0, 0, 0, newYork),
+			next:     time.Date(2000, 7, 2, 0, 0, 0, 0, newYork),
+		}, {
+			name:     "custom interval and epoch",
+			schedule: RotateSchedule{Interval: 15 * time.Minute, Epoch: time.Date(2000, 1, 1, 0, 5, 0, 0, time.UTC)},
+			now:      time.Date(2000, 1, 2, 3, 4, 5, 6, time.UTC),
+			next:     time.Date(2000, 1, 2, 3, 5, 0, 0, time.UTC),
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			next := testCase.schedule.Next(testCase.now)
+			assert.True(t, testCase.next.Equal(next), "expected %s, but got %s", testCase.next, next)
+		})
+	}
+}
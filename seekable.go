@@ -0,0 +1,347 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Constants of the zstd seekable format.
+// See https://github.com/facebook/zstd/blob/dev/contrib/seekable_format/zstd_seekable_compression_format.md
+const (
+	seekTableSkippableMagic = zstdSkippableMagic | 0xE
+	seekTableMagic          = 0x8F92EAB1
+	seekTableFooterSize     = 9
+	seekTableEntrySize      = 8
+	// seekTableChecksumFlag is set in the descriptor if entries have checksums.
+	seekTableChecksumFlag = 1 << 7
+)
+
+var errNoSeekTable = errors.New("seek table not found")
+
+// seekEntry is the sizes of a frame.
+type seekEntry struct {
+	compressed   uint32
+	decompressed uint32
+}
+
+// seekTable is the list of frames from the beginning of the file.
+type seekTable []seekEntry
+
+// appendFrame encodes the table as a skippable frame.
+func (t seekTable) appendFrame(buf []byte) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, seekTableSkippableMagic)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(t)*seekTableEntrySize+seekTableFooterSize))
+	for _, e := range t {
+		buf = binary.LittleEndian.AppendUint32(buf, e.compressed)
+		buf = binary.LittleEndian.AppendUint32(buf, e.decompressed)
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(t)))
+	buf = append(buf, 0) // Descriptor without checksums.
+	return binary.LittleEndian.AppendUint32(buf, seekTableMagic)
+}
+
+// compressedSize returns the total size of frames.
+func (t seekTable) compressedSize() int64 {
+	var size int64
+	for _, e := range t {
+		size += int64(e.compressed)
+	}
+	return size
+}
+
+// readSeekTable reads the seek table at the end of r.
+// It returns the table and the size of the skippable frame containing it.
+// The table is valid only if it covers all frames before it.
+func readSeekTable(r io.ReaderAt, size int64) (seekTable, int64, error) {
+	if size < 8+seekTableFooterSize {
+		return nil, 0, errNoSeekTable
+	}
+	footer := make([]byte, seekTableFooterSize)
+	if _, err := r.ReadAt(footer, size-seekTableFooterSize); err != nil {
+		return nil, 0, err
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != seekTableMagic {
+		return nil, 0, errNoSeekTable
+	}
+	n := int64(binary.LittleEndian.Uint32(footer))
+	entrySize := int64(seekTableEntrySize)
+	if footer[4]&seekTableChecksumFlag != 0 {
+		entrySize += 4
+	}
+	frameSize := 8 + n*entrySize + seekTableFooterSize
+	if size < frameSize {
+		return nil, 0, errNoSeekTable
+	}
+	frame := make([]byte, frameSize-seekTableFooterSize)
+	if _, err := r.ReadAt(frame, size-frameSize); err != nil {
+		return nil, 0, err
+	}
+	if binary.LittleEndian.Uint32(frame) != seekTableSkippableMagic ||
+		int64(binary.LittleEndian.Uint32(frame[4:])) != frameSize-8 {
+		return nil, 0, errNoSeekTable
+	}
+	t := make(seekTable, n)
+	for i := range t {
+		e := frame[8+int64(i)*entrySize:]
+		t[i] = seekEntry{
+			compressed:   binary.LittleEndian.Uint32(e),
+			decompressed: binary.LittleEndian.Uint32(e[4:]),
+		}
+	}
+	if t.compressedSize()+frameSize != size {
+		return nil, 0, errNoSeekTable
+	}
+	return t, frameSize, nil
+}
+
+// scanSeekTable builds the seek table by decompressing all frames in r.
+// If a frame is damaged, it returns the table of frames before it with an error.
+func scanSeekTable(r io.Reader, a *ZstdAlgorithm) (seekTable, error) {
+	br := bufio.NewReader(r)
+	var t seekTable
+	out := bytes.Buffer{}
+	for {
+		out.Reset()
+		n, err := a.ReadFrame(br, &out)
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return t, err
+		}
+		t = append(t, seekEntry{compressed: uint32(n), decompressed: uint32(out.Len())})
+	}
+}
+
+// loadSeekTable returns the seek table of the file.
+// If the file has no valid seek table, it scans all frames.
+// A seek table left in the middle of the file is indexed as a frame without data.
+func loadSeekTable(filePath string, a *ZstdAlgorithm) (seekTable, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	t, tableSize, err := readSeekTable(f, stat.Size())
+	if err == nil {
+		return append(t, seekEntry{compressed: uint32(tableSize)}), nil
+	}
+	return scanSeekTable(f, a)
+}
+
+// NewSeekableWriter returns a writer which writes the zstd seekable format.
+// Each write is compressed as a frame, and the seek table is written on Close.
+// If w writes to a file containing frames, the seek table also covers them.
+// If the existing frames cannot be indexed, the seek table is not written and readers scan frames instead.
+func NewSeekableWriter(w io.WriteCloser, a *ZstdAlgorithm) io.WriteCloser {
+	s := &SeekableWriter{
+		w:     w,
+		a:     a,
+		valid: true,
+	}
+	if f := fileOf(w); f != nil {
+		if stat, err := f.Stat(); err == nil && 0 < stat.Size() {
+			var err error
+			s.table, err = loadSeekTable(f.Name(), a)
+			s.valid = err == nil
+		}
+	}
+	return s
+}
+
+// SeekableWriter compresses data into zstd frames, and records their sizes to the seek table.
+type SeekableWriter struct {
+	w     io.WriteCloser
+	a     *ZstdAlgorithm
+	buf   bytes.Buffer
+	table seekTable
+	// valid is false if the seek table does not cover all frames in the file.
+	valid bool
+}
+
+func (s *SeekableWriter) Write(p []byte) (n int, err error) {
+	err = s.a.Compress(p, &s.buf)
+	if err == nil {
+		n, err = s.w.Write(s.buf.Bytes())
+		if err == nil {
+			s.table = append(s.table, seekEntry{compressed: uint32(n), decompressed: uint32(len(p))})
+		} else {
+			// The partial frame breaks the seek table.
+			s.valid = false
+		}
+	}
+	s.buf.Reset()
+	if err == nil {
+		n = len(p)
+	} else {
+		n = 0
+	}
+	return
+}
+
+func (s *SeekableWriter) Close() error {
+	var err error
+	if s.valid && 0 < len(s.table) {
+		_, err = s.w.Write(s.table.appendFrame(nil))
+	}
+	if cerr := s.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// seekFrame is the position of a frame.
+type seekFrame struct {
+	offset      int64
+	size        int64
+	plainOffset int64
+	plainSize   int64
+}
+
+// OpenSeekableReader opens the zstd file for random access by uncompressed offset.
+// If the file has no seek table (e.g. the writer crashed), all frames are scanned to build the index.
+// Damaged frames at the end of the file are excluded from the index.
+func OpenSeekableReader(filePath string) (*SeekableReader, error) {
+	z, ok := readerAlgorithm(filePath).(*ZstdAlgorithm)
+	if !ok {
+		return nil, fmt.Errorf("%s: seekable format is only supported by zstd", filePath)
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &SeekableReader{f: f, a: z}
+	t, _, err := readSeekTable(f, stat.Size())
+	if err == nil {
+		s.HasSeekTable = true
+	} else {
+		// Ignore damaged frames.
+		t, _ = scanSeekTable(f, z)
+	}
+	var offset, plainOffset int64
+	for _, e := range t {
+		if 0 < e.decompressed {
+			s.frames = append(s.frames, seekFrame{
+				offset:      offset,
+				size:        int64(e.compressed),
+				plainOffset: plainOffset,
+				plainSize:   int64(e.decompressed),
+			})
+		}
+		offset += int64(e.compressed)
+		plainOffset += int64(e.decompressed)
+	}
+	s.size = plainOffset
+	return s, nil
+}
+
+// SeekableReader reads a zstd file at any uncompressed offset.
+// It decompresses only frames containing the requested range.
+type SeekableReader struct {
+	// HasSeekTable is true if the index was read from the seek table.
+	HasSeekTable bool
+	f            *os.File
+	a            *ZstdAlgorithm
+	frames       []seekFrame
+	size         int64
+	offset       int64
+	// cache is the last decompressed frame.
+	cache      bytes.Buffer
+	cacheFrame int
+	compressed []byte
+}
+
+// Size returns the uncompressed size of the file.
+func (s *SeekableReader) Size() int64 {
+	return s.size
+}
+
+func (s *SeekableReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	for n < len(p) {
+		if s.size <= off {
+			return n, io.EOF
+		}
+		i := sort.Search(len(s.frames), func(i int) bool {
+			return off < s.frames[i].plainOffset+s.frames[i].plainSize
+		})
+		data, err := s.frame(i)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], data[off-s.frames[i].plainOffset:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+func (s *SeekableReader) Read(p []byte) (n int, err error) {
+	n, err = s.ReadAt(p, s.offset)
+	s.offset += int64(n)
+	if 0 < n && err == io.EOF {
+		err = nil
+	}
+	return
+}
+
+func (s *SeekableReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	s.offset = offset
+	return offset, nil
+}
+
+func (s *SeekableReader) Close() error {
+	return s.f.Close()
+}
+
+// frame returns the decompressed data of the i-th frame.
+func (s *SeekableReader) frame(i int) ([]byte, error) {
+	if s.cacheFrame == i+1 {
+		return s.cache.Bytes(), nil
+	}
+	fr := s.frames[i]
+	s.cacheFrame = 0
+	s.cache.Reset()
+	s.compressed = append(s.compressed[:0], make([]byte, fr.size)...)
+	if _, err := s.f.ReadAt(s.compressed, fr.offset); err != nil {
+		return nil, &FrameError{Offset: fr.offset, Err: err}
+	}
+	if err := s.a.Decompress(s.compressed, &s.cache); err != nil {
+		return nil, &FrameError{Offset: fr.offset, Err: err}
+	}
+	if int64(s.cache.Len()) != fr.plainSize {
+		return nil, &FrameError{Offset: fr.offset, Err: fmt.Errorf("%w: size mismatch with the seek table", errInvalidZstdFrame)}
+	}
+	// cacheFrame is 1-based so that the zero value means no cache.
+	s.cacheFrame = i + 1
+	return s.cache.Bytes(), nil
+}
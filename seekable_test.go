@@ -0,0 +1,181 @@
+package logwriter
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// seekableLines returns lines written to seekable files in tests.
+func seekableLines(from, to int) string {
+	s := strings.Builder{}
+	for i := from; i < to; i++ {
+		fmt.Fprintf(&s, "line %04d\n", i)
+	}
+	return s.String()
+}
+
+// writeSeekable writes lines to the file with Seekable option. Each line is written in a frame.
+func writeSeekable(t *testing.T, filePath string, from, to int) {
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.BufferSize = 0
+	opt.Seekable = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := from; i < to; i++ {
+		_, err = io.WriteString(w, seekableLines(i, i+1))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+}
+
+func assertSeekable(t *testing.T, filePath string, hasSeekTable bool, expected string) {
+	r, err := OpenSeekableReader(filePath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	assert.Equal(t, hasSeekTable, r.HasSeekTable)
+	assert.Equal(t, int64(len(expected)), r.Size())
+
+	// Read across frame boundaries.
+	p := make([]byte, 25)
+	n, err := r.ReadAt(p, 15)
+	assert.NoError(t, err)
+	assert.Equal(t, expected[15:40], string(p[:n]))
+	n, err = r.ReadAt(p, int64(len(expected)-5))
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, expected[len(expected)-5:], string(p[:n]))
+
+	offset, err := r.Seek(-20, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(expected)-20), offset)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, expected[len(expected)-20:], string(data))
+
+	// The file is also readable by a sequential reader.
+	sr, err := OpenReader(filePath)
+	if assert.NoError(t, err) {
+		data, err = io.ReadAll(sr)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+		sr.Close()
+	}
+}
+
+func TestSeekableWriter(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	writeSeekable(t, filePath, 0, 10)
+	assertSeekable(t, filePath, true, seekableLines(0, 10))
+
+	// Appended frames are covered by the new seek table.
+	writeSeekable(t, filePath, 10, 20)
+	assertSeekable(t, filePath, true, seekableLines(0, 20))
+}
+
+func TestSeekableReader_withoutSeekTable(t *testing.T) {
+	// The writer crashed before writing the seek table, and the last frame was truncated.
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	var frames []string
+	for i := 0; i < 10; i++ {
+		frames = append(frames, seekableLines(i, i+1))
+	}
+	data := compressFrames(t, &ZstdAlgorithm{}, frames)
+	assert.NoError(t, os.WriteFile(filePath, data[:len(data)-3], 0666))
+	assertSeekableFallback := func(expected string) {
+		r, err := OpenSeekableReader(filePath)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer r.Close()
+		assert.False(t, r.HasSeekTable)
+		all, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(all))
+	}
+	assertSeekableFallback(seekableLines(0, 9))
+
+	// Appending to the damaged file does not write an invalid seek table.
+	writeSeekable(t, filePath, 10, 11)
+	damaged, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	_, _, err = readSeekTable(bytes.NewReader(damaged), int64(len(damaged)))
+	assert.Equal(t, errNoSeekTable, err)
+
+	// Appending to the file without damage restores the seek table.
+	assert.NoError(t, os.WriteFile(filePath, data, 0666))
+	writeSeekable(t, filePath, 10, 20)
+	assertSeekable(t, filePath, true, seekableLines(0, 20))
+}
+
+func Test_seekTable_appendFrame(t *testing.T) {
+	table := seekTable{{compressed: 10, decompressed: 20}, {compressed: 30, decompressed: 40}}
+	frame := table.appendFrame(nil)
+	assert.Equal(t, []byte{
+		0x5E, 0x2A, 0x4D, 0x18, // Skippable magic number
+		25, 0, 0, 0, // Frame size
+		10, 0, 0, 0, 20, 0, 0, 0,
+		30, 0, 0, 0, 40, 0, 0, 0,
+		2, 0, 0, 0, // Number of frames
+		0,                      // Descriptor
+		0xB1, 0xEA, 0x92, 0x8F, // Seekable magic number
+	}, frame)
+
+	file := append(make([]byte, 40), frame...)
+	parsed, size, err := readSeekTable(bytes.NewReader(file), int64(len(file)))
+	assert.NoError(t, err)
+	assert.Equal(t, table, parsed)
+	assert.Equal(t, int64(len(frame)), size)
+
+	// The table does not match the file size.
+	_, _, err = readSeekTable(bytes.NewReader(file[1:]), int64(len(file)-1))
+	assert.Equal(t, errNoSeekTable, err)
+}
+
+func TestOpen_Seekable(t *testing.T) {
+	opt := DefaultOpenOption
+	opt.Seekable = true
+	opt.FileOrDir = filepath.Join(t.TempDir(), "test.log.gz")
+	_, err := Open(opt)
+	assert.Error(t, err)
+
+	_, err = OpenSeekableReader(opt.FileOrDir)
+	assert.Error(t, err)
+}
+
+func TestOpen_Seekable_dictionary(t *testing.T) {
+	dict, err := BuildDictionary(toBytes(sampleFrames(100)), 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	opt := DefaultOpenOption
+	opt.FileOrDir = filepath.Join(t.TempDir(), "test.log.zst")
+	opt.Compression.Dictionary = dict
+	opt.Seekable = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = io.WriteString(w, sampleFrames(1)[0])
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	r, err := OpenSeekableReader(opt.FileOrDir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	assert.True(t, r.HasSeekTable)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleFrames(1)[0], string(data))
+}
@@ -0,0 +1,119 @@
+package logwriter
+
+import (
+	"io"
+)
+
+// DefaultMaxFrameSize is the default limit of uncompressed bytes in a frame written by StreamCompressedWriter.
+const DefaultMaxFrameSize = 1 << 20 // 1MiB
+
+var (
+	_ StreamAlgorithm = &NopAlgorithm{}
+	_ StreamAlgorithm = &GzipAlgorithm{}
+	_ StreamAlgorithm = &ZstdAlgorithm{}
+	_ StreamAlgorithm = &DeflateAlgorithm{}
+	_ StreamAlgorithm = &ZlibAlgorithm{}
+)
+
+// StreamAlgorithm is implemented by Algorithm which can compress multiple writes in one frame.
+// S2 and Snappy do not implement it, because flushed chunks have no stream identifier and
+// readers following the file (e.g. FileFollower) cannot resume in the middle of the stream.
+type StreamAlgorithm interface {
+	Algorithm
+	// NewEncoder returns an encoder which writes one frame to w.
+	// The frame is completed when the encoder is closed.
+	NewEncoder(w io.Writer) (StreamEncoder, error)
+}
+
+// StreamEncoder compresses data in one frame.
+type StreamEncoder interface {
+	io.Writer
+	// Flush writes all pending data to the underlying writer without completing the frame.
+	Flush() error
+	// Close completes the frame. It does not close the underlying writer.
+	Close() error
+}
+
+// NewStreamCompressedWriter returns a writer which keeps the compression context across writes.
+// Each write is flushed to w, and the frame is completed when it contains maxFrameSize bytes or more.
+// If maxFrameSize is not a positive value, DefaultMaxFrameSize is used.
+func NewStreamCompressedWriter(w io.WriteCloser, a StreamAlgorithm, maxFrameSize int64) io.WriteCloser {
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+	return &StreamCompressedWriter{
+		MaxFrameSize: maxFrameSize,
+		w:            w,
+		a:            a,
+	}
+}
+
+// StreamCompressedWriter compresses data in long-lived frames.
+// Compared with CompressedWriter, the compression ratio of small writes is better,
+// but readers cannot decompress the frame until it is completed.
+// After a crash, Repair removes the incomplete frame, so at most MaxFrameSize bytes are lost.
+type StreamCompressedWriter struct {
+	// MaxFrameSize is the limit of uncompressed bytes in a frame.
+	MaxFrameSize int64
+	w            io.WriteCloser
+	a            StreamAlgorithm
+	enc          StreamEncoder
+	// frameSize is the uncompressed bytes written to the current frame.
+	frameSize int64
+	err       error
+}
+
+func (s *StreamCompressedWriter) Write(p []byte) (n int, err error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if s.enc == nil {
+		s.enc, s.err = s.a.NewEncoder(s.w)
+		if s.err != nil {
+			s.enc = nil
+			return 0, s.err
+		}
+	}
+	n, err = s.enc.Write(p)
+	s.frameSize += int64(n)
+	if err == nil {
+		if s.MaxFrameSize <= s.frameSize {
+			err = s.endFrame()
+		} else {
+			err = s.enc.Flush()
+		}
+	}
+	s.err = err
+	return
+}
+
+// Flush writes pending data to the underlying writer.
+func (s *StreamCompressedWriter) Flush() error {
+	if s.err != nil || s.enc == nil {
+		return s.err
+	}
+	s.err = s.enc.Flush()
+	return s.err
+}
+
+func (s *StreamCompressedWriter) Close() error {
+	err := s.err
+	if err == nil && s.enc != nil {
+		err = s.endFrame()
+	}
+	if cerr := s.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// endFrame completes the current frame. The next write starts a new frame.
+func (s *StreamCompressedWriter) endFrame() error {
+	err := s.enc.Close()
+	s.enc = nil
+	s.frameSize = 0
+	return err
+}
@@ -0,0 +1,164 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamCompressedWriter(t *testing.T) {
+	for name, a := range frameAlgorithms() {
+		sa, ok := a.(StreamAlgorithm)
+		if !ok {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := NewStreamCompressedWriter(&nopCloserWriter{buf}, sa, 10)
+			for i := 0; i < 7; i++ {
+				n, err := w.Write([]byte("foo\n"))
+				assert.NoError(t, err)
+				assert.Equal(t, 4, n)
+			}
+
+			// The data before the incomplete frame is readable.
+			data, err := io.ReadAll(NewCompressedReader(bytes.NewReader(buf.Bytes()), a))
+			var frameErr *FrameError
+			if assert.ErrorAs(t, err, &frameErr) {
+				assert.True(t, frameErr.Truncated())
+			}
+			assert.Equal(t, strings.Repeat("foo\n", 6), string(data))
+
+			assert.NoError(t, w.Close())
+			data, err = io.ReadAll(NewCompressedReader(bytes.NewReader(buf.Bytes()), a))
+			assert.NoError(t, err)
+			assert.Equal(t, strings.Repeat("foo\n", 7), string(data))
+			assert.Equal(t, 3, countFrames(t, a.(FrameAlgorithm), buf.Bytes()))
+		})
+	}
+}
+
+func TestStreamCompressedWriter_ratio(t *testing.T) {
+	stream := &bytes.Buffer{}
+	w := NewStreamCompressedWriter(&nopCloserWriter{stream}, &ZstdAlgorithm{}, 0)
+	var frames []string
+	for i := 0; i < 100; i++ {
+		frames = append(frames, "level=info msg=\"request completed\" path=/api/v1/users\n")
+		_, err := io.WriteString(w, frames[i])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	assert.Less(t, stream.Len()*4, len(compressFrames(t, &ZstdAlgorithm{}, frames)))
+}
+
+func TestOpen_StreamCompression(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.StreamCompression = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 100; i++ {
+		_, err = w.Write([]byte("0123456789\n"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	r, err := OpenReader(filePath)
+	if assert.NoError(t, err) {
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("0123456789\n", 100), string(data))
+		r.Close()
+	}
+
+	opt.CompressionWorkers = 2
+	_, err = Open(opt)
+	assert.Error(t, err)
+
+	// Streams of S2 and Snappy cannot be followed.
+	opt.CompressionWorkers = 0
+	for _, suffix := range []string{".s2", ".sz"} {
+		opt.FileOrDir = filepath.Join(filepath.Dir(filePath), "test.log"+suffix)
+		_, err = Open(opt)
+		assert.Error(t, err, suffix)
+	}
+}
+
+func TestFileFollower_Read_streamCompression(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log.zst")
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.BufferSize = 0
+	opt.StreamCompression = true
+	opt.MaxFrameSize = 12
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Close()
+	follower, err := FollowFile(filePath, 0, 10*time.Millisecond)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer follower.Close()
+
+	received := make(chan string)
+	go func() {
+		buf := make([]byte, 100)
+		for {
+			n, err := follower.Read(buf)
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- string(buf[:n])
+		}
+	}()
+	receive := func() string {
+		select {
+		case data, ok := <-received:
+			assert.True(t, ok, "follower failed")
+			return data
+		case <-time.After(time.Second):
+			assert.FailNow(t, "timeout exceeded")
+			return ""
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		// The frame is completed by the second write.
+		_, err = io.WriteString(w, "line1\n")
+		assert.NoError(t, err)
+		select {
+		case data := <-received:
+			assert.Failf(t, "incomplete frame was read", "%q", data)
+		case <-time.After(50 * time.Millisecond):
+		}
+		_, err = io.WriteString(w, "line2\n")
+		assert.NoError(t, err)
+		assert.Equal(t, "line1\nline2\n", receive())
+	}
+}
+
+// countFrames returns the number of frames in data.
+func countFrames(t *testing.T, a FrameAlgorithm, data []byte) int {
+	r := bufio.NewReader(bytes.NewReader(data))
+	out := bytes.Buffer{}
+	for n := 0; ; n++ {
+		_, err := a.ReadFrame(r, &out)
+		if err == io.EOF {
+			return n
+		}
+		if !assert.NoError(t, err) {
+			return n
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package logwriter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+// Tail returns the last n lines of the log file.
+// It also returns the offset of the end of the last complete frame, which can be passed to FollowFile.
+// A truncated frame at the end of file is ignored because the writer may be writing it.
+//
+// If the algorithm can find frame boundaries without decompression (e.g. zstd), only the last frames are decompressed.
+// Otherwise, the whole file is decompressed.
+func Tail(filePath string, n int) (lines []byte, offset int64, err error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	a := readerAlgorithm(filePath)
+	if s, ok := a.(frameSkipper); ok {
+		if fa, ok := a.(FrameAlgorithm); ok {
+			return tailFrames(f, fa, s, n)
+		}
+	}
+	return tailAll(f, a, n)
+}
+
+// tailFrames finds frame boundaries, and decompresses frames from the end of file until n lines are collected.
+func tailFrames(f *os.File, a FrameAlgorithm, s frameSkipper, n int) ([]byte, int64, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := stat.Size()
+	offsets := []int64{0}
+	for {
+		frameSize, err := s.SkipFrame(f, size)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, &FrameError{Offset: offsets[len(offsets)-1], Err: err}
+		}
+		offsets = append(offsets, offsets[len(offsets)-1]+frameSize)
+	}
+
+	end := offsets[len(offsets)-1]
+	var data []byte
+	frame := bytes.Buffer{}
+	for i := len(offsets) - 2; 0 <= i && countLines(data) <= n; i-- {
+		if _, err = f.Seek(offsets[i], io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		frame.Reset()
+		r := bufio.NewReader(io.LimitReader(f, offsets[i+1]-offsets[i]))
+		if _, err = a.ReadFrame(r, &frame); err != nil {
+			return nil, 0, &FrameError{Offset: offsets[i], Err: err}
+		}
+		data = append(bytes.Clone(frame.Bytes()), data...)
+	}
+	return lastLines(data, n), end, nil
+}
+
+// tailAll decompresses whole file and keeps the last n lines.
+func tailAll(f *os.File, a Algorithm, n int) ([]byte, int64, error) {
+	r := NewTolerantReader(f, a)
+	var data []byte
+	buf := make([]byte, 32*(1<<10))
+	for {
+		m, err := r.Read(buf)
+		data = lastLines(append(data, buf[:m]...), n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if d := r.Damage(); d != nil && !d.Truncated() {
+		return nil, 0, d
+	}
+	return data, r.Offset(), nil
+}
+
+// countLines returns the number of lines in data.
+// The last line without newline is also counted.
+func countLines(data []byte) int {
+	lines := bytes.Count(data, []byte{'\n'})
+	if 0 < len(data) && data[len(data)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// lastLines returns the last n lines of data.
+func lastLines(data []byte, n int) []byte {
+	if n <= 0 {
+		return data[len(data):]
+	}
+	end := len(data)
+	if 0 < end && data[end-1] == '\n' {
+		// Do not count the newline at the end of data.
+		end--
+	}
+	for i := end - 1; 0 <= i; i-- {
+		if data[i] == '\n' {
+			n--
+			if n == 0 {
+				return data[i+1:]
+			}
+		}
+	}
+	return data
+}
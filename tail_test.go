@@ -0,0 +1,59 @@
+package logwriter
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_lastLines(t *testing.T) {
+	cases := []struct {
+		data     string
+		n        int
+		expected string
+	}{
+		{data: "", n: 1, expected: ""},
+		{data: "a\nb\nc\n", n: 0, expected: ""},
+		{data: "a\nb\nc\n", n: 2, expected: "b\nc\n"},
+		{data: "a\nb\nc", n: 2, expected: "b\nc"},
+		{data: "a\nb\nc\n", n: 3, expected: "a\nb\nc\n"},
+		{data: "a\nb\nc\n", n: 10, expected: "a\nb\nc\n"},
+		{data: "\n\n", n: 1, expected: "\n"},
+	}
+	for _, testCase := range cases {
+		assert.Equal(t, testCase.expected, string(lastLines([]byte(testCase.data), testCase.n)), "%q", testCase.data)
+	}
+}
+
+func TestTail(t *testing.T) {
+	// Lines are split across frames.
+	frames := []string{"line 1\nli", "ne 2\n", "", "line 3\nline 4\n", "line 5\nline 6\n"}
+	for _, suffix := range []string{"", ".gz", ".zst"} {
+		t.Run(fmt.Sprintf("suffix=%q", suffix), func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "test.log"+suffix)
+			data := compressFrames(t, readerAlgorithm(filePath), frames)
+			// The last frame is being written.
+			truncated := data[:len(data)-3]
+			if suffix == "" {
+				truncated = data
+			}
+			assert.NoError(t, os.WriteFile(filePath, truncated, 0666))
+
+			lines, offset, err := Tail(filePath, 3)
+			assert.NoError(t, err)
+			if suffix == "" {
+				assert.Equal(t, "line 4\nline 5\nline 6\n", string(lines))
+				assert.Equal(t, int64(len(data)), offset)
+				return
+			}
+			assert.Equal(t, "line 2\nline 3\nline 4\n", string(lines))
+			assert.Equal(t, int64(len(compressFrames(t, readerAlgorithm(filePath), frames[:4]))), offset)
+
+			lines, _, err = Tail(filePath, 10)
+			assert.NoError(t, err)
+			assert.Equal(t, "line 1\nline 2\nline 3\nline 4\n", string(lines))
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package logwriter
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileNameTemplate generates file names from OpenOption.FilenameTemplate.
+type fileNameTemplate struct {
+	parts    []templatePart
+	hasSeq   bool
+	prefix   string
+	hostname string
+	pid      string
+}
+
+// templatePart is a literal text or a placeholder in the template.
+type templatePart struct {
+	literal string
+	// name is the name of the placeholder. It is empty if the part is a literal.
+	name string
+	// layout is the time layout of "{time:LAYOUT}".
+	layout string
+}
+
+// parseFileNameTemplate parses opt.FilenameTemplate.
+// It returns nil if the template is empty.
+func parseFileNameTemplate(opt OpenOption) (*fileNameTemplate, error) {
+	s := opt.FilenameTemplate
+	if s == "" {
+		return nil, nil
+	}
+	t := &fileNameTemplate{
+		prefix: opt.Prefix,
+		pid:    strconv.Itoa(os.Getpid()),
+	}
+	for s != "" {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			t.parts = append(t.parts, templatePart{literal: s})
+			break
+		}
+		if 0 < i {
+			t.parts = append(t.parts, templatePart{literal: s[:i]})
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in FilenameTemplate: %q", opt.FilenameTemplate)
+		}
+		placeholder := s[i+1 : i+j]
+		s = s[i+j+1:]
+
+		name, layout, hasLayout := strings.Cut(placeholder, ":")
+		switch {
+		case name == "time" && hasLayout && layout != "":
+		case name == "prefix" || name == "hostname" || name == "pid" || name == "seq":
+			if hasLayout {
+				return nil, fmt.Errorf("placeholder {%s} does not take a format in FilenameTemplate", placeholder)
+			}
+		default:
+			return nil, fmt.Errorf("unknown placeholder {%s} in FilenameTemplate", placeholder)
+		}
+		switch name {
+		case "seq":
+			t.hasSeq = true
+		case "hostname":
+			if t.hostname == "" {
+				hostname, err := os.Hostname()
+				if err != nil {
+					return nil, err
+				}
+				t.hostname = hostname
+			}
+		}
+		t.parts = append(t.parts, templatePart{name: name, layout: layout})
+	}
+
+	// Check the name stays in the directory. Placeholders may not contain '/' except for time layouts.
+	name := t.expand(time.Now(), 0)
+	if !filepath.IsLocal(name) || strings.HasSuffix(name, "/") {
+		return nil, fmt.Errorf("FilenameTemplate must be a relative file path: %q", opt.FilenameTemplate)
+	}
+	return t, nil
+}
+
+// expand returns the file name at now with the sequence number.
+// If the template has no {seq} and seq is positive, ".<seq>" is inserted before the extension.
+func (t *fileNameTemplate) expand(now time.Time, seq int) string {
+	var b strings.Builder
+	for _, p := range t.parts {
+		switch p.name {
+		case "":
+			b.WriteString(p.literal)
+		case "prefix":
+			b.WriteString(t.prefix)
+		case "hostname":
+			b.WriteString(t.hostname)
+		case "pid":
+			b.WriteString(t.pid)
+		case "seq":
+			b.WriteString(strconv.Itoa(seq))
+		case "time":
+			b.WriteString(now.Format(p.layout))
+		}
+	}
+	name := b.String()
+	if t.hasSeq || seq == 0 {
+		return name
+	}
+	ext := path.Ext(name)
+	if strings.ContainsRune(ext, '/') {
+		ext = ""
+	}
+	return fmt.Sprintf("%s.%d%s", name[:len(name)-len(ext)], seq, ext)
+}
+
+// create creates a new file in dir whose name is generated at now and followed by suffix.
+// Missing directories in the name are created.
+// The file is created exclusively. If the name already exists, the next sequence number is used.
+func (t *fileNameTemplate) create(dir, suffix string, now time.Time, flag int, mode os.FileMode) (*os.File, string, error) {
+	for seq := 0; ; seq++ {
+		filePath := filepath.Join(dir, t.expand(now, seq)+suffix)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+			return nil, "", err
+		}
+		f, err := os.OpenFile(filePath, flag|os.O_CREATE|os.O_EXCL, mode)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return f, filePath, nil
+	}
+}
@@ -0,0 +1,138 @@
+package logwriter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_fileNameTemplate_expand(t *testing.T) {
+	now := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	hostname, err := os.Hostname()
+	if !assert.NoError(t, err) {
+		return
+	}
+	pid := strconv.Itoa(os.Getpid())
+	cases := []struct {
+		template string
+		seq      int
+		expected string
+	}{
+		{"{prefix}.log", 0, "app.log"},
+		{"{prefix}.log", 2, "app.2.log"},
+		{"{prefix}", 1, "app.1"},
+		{"{prefix}-{seq}.log", 0, "app-0.log"},
+		{"{prefix}-{seq}.log", 3, "app-3.log"},
+		{"{hostname}/{pid}.log", 0, hostname + "/" + pid + ".log"},
+		{"{time:2006/01/02}/{prefix}.{time:15}.log", 0, "2000/01/02/app.03.log"},
+		{"{time:2006-01-02T15}.d/app", 1, "2000-01-02T03.d/app.1"},
+	}
+	for _, c := range cases {
+		tmpl, err := parseFileNameTemplate(OpenOption{Prefix: "app", FilenameTemplate: c.template})
+		if !assert.NoError(t, err, c.template) {
+			continue
+		}
+		assert.Equal(t, c.expected, tmpl.expand(now, c.seq), c.template)
+	}
+}
+
+func Test_parseFileNameTemplate_invalid(t *testing.T) {
+	for _, template := range []string{
+		"{prefix",
+		"{unknown}.log",
+		"{time}.log",
+		"{time:}.log",
+		"{pid:2006}.log",
+		"/var/log/{prefix}.log",
+		"../{prefix}.log",
+		"{time:2006}/",
+	} {
+		_, err := parseFileNameTemplate(OpenOption{Prefix: "app", FilenameTemplate: template})
+		assert.Error(t, err, template)
+	}
+}
+
+func TestOpen_FilenameTemplate(t *testing.T) {
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "test"
+	opt.FilenameTemplate = "{time:2006}/{prefix}.log"
+	opt.BufferSize = 10
+	opt.MaxFileSize = 20
+	opt.UncompressedFileSize = true
+	opt.CurrentLink = true
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	line := "0123456789\n"
+	for i := 0; i < 6; i++ {
+		_, err = w.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+
+	// Names collided with existing files have sequence numbers.
+	sub := filepath.Join(dir, time.Now().Format("2006"))
+	for _, name := range []string{"test.log.zst", "test.1.log.zst", "test.2.log.zst"} {
+		assert.Equal(t, line+line, readLogFile(t, filepath.Join(sub, name)), name)
+	}
+	entries, err := os.ReadDir(sub)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	target, err := os.Readlink(filepath.Join(dir, "test.current.log.zst"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Base(sub), "test.2.log.zst"), target)
+}
+
+func TestOpen_FilenameTemplate_exclusive(t *testing.T) {
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "test"
+	opt.FilenameTemplate = "{prefix}-{seq}.log"
+	for i := 0; i < 2; i++ {
+		w, err := Open(opt)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = w.Write([]byte("foo\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	}
+	// The existing file is not appended.
+	assert.Equal(t, "foo\n", readLogFile(t, filepath.Join(dir, "test-0.log.zst")))
+	assert.Equal(t, "foo\n", readLogFile(t, filepath.Join(dir, "test-1.log.zst")))
+
+	opt.MaxFiles = 1
+	_, err := Open(opt)
+	assert.Error(t, err)
+}
+
+func TestOpen_FilenameTemplate_dictionary(t *testing.T) {
+	dict, err := BuildDictionary(toBytes(sampleFrames(100)), 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dir := t.TempDir()
+	opt := DefaultOpenOption
+	opt.FileOrDir = dir
+	opt.Prefix = "test"
+	opt.FilenameTemplate = "{time:2006}/{prefix}.log"
+	opt.Compression.Dictionary = dict
+	opt.MaxFileSize = 1 << 20
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = w.Write([]byte(sampleFrames(1)[0]))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	filePath := filepath.Join(dir, time.Now().Format("2006"), "test.log.zst")
+	assert.Equal(t, sampleFrames(1)[0], readLogFile(t, filePath))
+}
@@ -12,10 +12,16 @@ import (
 // It calls w.Write(nil) at the specified interval to flush the buffer.
 // Additionally, it protects w from concurrent Write and Close method calls.
 func NewTickWriter(w io.WriteCloser, interval time.Duration) io.WriteCloser {
+	return newTickWriter(w, interval, false)
+}
+
+// newTickWriter creates a TickWriter. If watch is true, it also checks the file being written at the interval.
+func newTickWriter(w io.WriteCloser, interval time.Duration, watch bool) *TickWriter {
 	ctx, cancel := context.WithCancel(context.Background())
 	tw := &TickWriter{
 		w:        w,
 		interval: interval,
+		watch:    watch,
 		ctx:      ctx,
 		cancel:   cancel,
 	}
@@ -35,6 +41,7 @@ func NewTickWriter(w io.WriteCloser, interval time.Duration) io.WriteCloser {
 type TickWriter struct {
 	w        io.WriteCloser
 	interval time.Duration
+	watch    bool
 	ctx      context.Context
 	cancel   context.CancelFunc
 	mux      sync.Mutex
@@ -61,6 +68,26 @@ func (t *TickWriter) Close() error {
 	return t.w.Close()
 }
 
+// Reopen calls Reopen of w if w implements Reopener.
+func (t *TickWriter) Reopen() error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.closed {
+		return os.ErrClosed
+	}
+	return reopen(t.w)
+}
+
+// checkFile lets w check whether the file being written still exists.
+func (t *TickWriter) checkFile() error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.closed {
+		return os.ErrClosed
+	}
+	return checkFile(t.w)
+}
+
 func (t *TickWriter) worker() {
 	timer := time.NewTimer(t.interval)
 	for {
@@ -70,6 +97,9 @@ func (t *TickWriter) worker() {
 		case <-timer.C:
 			timer.Reset(t.interval)
 			t.Write(nil)
+			if t.watch {
+				t.checkFile()
+			}
 		}
 	}
 
@@ -78,3 +78,44 @@ func TestTickWriter_worker(t1 *testing.T) {
 		}
 	})
 }
+
+// blockingWriter blocks writes until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.release
+	return len(p), nil
+}
+
+func (b *blockingWriter) Close() error {
+	return nil
+}
+
+func TestTickWriter_backgroundFlush(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	buf := newBuffer(1<<20, 10*time.Millisecond, bw, time.Now)
+	buf.MaxInFlight = 100
+	tw := NewTickWriter(buf, 10*time.Millisecond)
+	_, err := tw.Write([]byte("foo"))
+	assert.NoError(t, err)
+	// Wait for the tick to hand the buffer to the background.
+	time.Sleep(50 * time.Millisecond)
+
+	// Writes do not wait for the background flush.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := tw.Write([]byte("bar"))
+		assert.NoError(t, err)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Write waited for the background flush")
+	}
+	close(bw.release)
+	<-done
+	assert.NoError(t, tw.Close())
+}
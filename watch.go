@@ -0,0 +1,67 @@
+package logwriter
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Reasons of FileEvent.
+const (
+	// FileRemoved means the file was removed or renamed, and nothing exists at the path.
+	FileRemoved = "removed"
+	// FileReplaced means another file exists at the path (e.g. renamed and recreated by other process).
+	FileReplaced = "replaced"
+)
+
+// FileEvent describes a log file reopened by the watchdog.
+type FileEvent struct {
+	// Path is the path to the file.
+	Path string
+	// Reason is FileRemoved or FileReplaced.
+	Reason string
+	// Time is when the watchdog detected the event.
+	Time time.Time
+}
+
+func (e FileEvent) String() string {
+	return fmt.Sprintf("%s was %s at %s", e.Path, e.Reason, e.Time.Format(time.RFC3339))
+}
+
+// fileChecker is implemented by writers which check whether the file being written still exists at its path.
+type fileChecker interface {
+	checkFile() error
+}
+
+// checkFile calls w.checkFile if w supports it. Otherwise it does nothing.
+func checkFile(w any) error {
+	if c, ok := w.(fileChecker); ok {
+		return c.checkFile()
+	}
+	return nil
+}
+
+// fileMoved compares the open file with the file at its path.
+// It returns the reason of FileEvent, or an empty string if the file is still at the path.
+func fileMoved(f *os.File) (string, error) {
+	opened, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	current, err := os.Stat(f.Name())
+	if os.IsNotExist(err) {
+		return FileRemoved, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !os.SameFile(opened, current) {
+		return FileReplaced, nil
+	}
+	return "", nil
+}
+
+// printFileEvent is the default handler of FileEvent.
+func printFileEvent(e FileEvent) {
+	fmt.Fprintf(os.Stderr, "logwriter: %s. reopened.\n", e)
+}
@@ -0,0 +1,128 @@
+package logwriter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpen_WatchFile(t *testing.T) {
+	t.Run("buffered", func(t *testing.T) {
+		testOpenWatchFile(t, DefaultOpenOption.BufferSize)
+	})
+	t.Run("unbuffered", func(t *testing.T) {
+		testOpenWatchFile(t, 0)
+	})
+}
+
+func testOpenWatchFile(t *testing.T, bufferSize int) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "test.log.zst")
+	events := make(chan FileEvent, 10)
+	opt := DefaultOpenOption
+	opt.FileOrDir = filePath
+	opt.BufferSize = bufferSize
+	opt.FlushInterval = 10 * time.Millisecond
+	opt.WatchFile = true
+	opt.OnFileEvent = func(e FileEvent) {
+		events <- e
+	}
+	w, err := Open(opt)
+	if !assert.NoError(t, err) {
+		return
+	}
+	waitEvent := func(reason string) {
+		select {
+		case e := <-events:
+			assert.Equal(t, filePath, e.Path)
+			assert.Equal(t, reason, e.Reason)
+		case <-time.After(5 * time.Second):
+			t.Error("timeout")
+		}
+	}
+
+	// Removed.
+	assert.NoError(t, os.Remove(filePath))
+	waitEvent(FileRemoved)
+	_, err = io.WriteString(w, "foo\n")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return readLogFile(t, filePath) == "foo\n"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Renamed and replaced by another file.
+	rotated := filepath.Join(dir, "test.1.log.zst")
+	assert.NoError(t, os.Rename(filePath, rotated))
+	assert.NoError(t, os.WriteFile(filePath, nil, 0666))
+	waitEvent(FileReplaced)
+	_, err = io.WriteString(w, "bar\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "foo\n", readLogFile(t, rotated))
+	assert.Equal(t, "bar\n", readLogFile(t, filePath))
+	assert.Len(t, events, 0)
+}
+
+func TestRotateWriter_checkFile_openError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	assert.NoError(t, os.Mkdir(dir, 0777))
+	filePath := filepath.Join(dir, "test.log")
+	var events []FileEvent
+	opt := RotateOption{
+		Watch: true,
+		OnFileEvent: func(e FileEvent) {
+			events = append(events, e)
+		},
+	}
+	open := func() (io.WriteCloser, error) {
+		return os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	}
+	w, err := NewRotateWriter(opt, open, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The next file cannot be opened while the directory is missing. The current file is kept.
+	assert.NoError(t, os.RemoveAll(dir))
+	assert.NoError(t, checkFile(w))
+	_, err = io.WriteString(w, "foo\n")
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+
+	// Retried after the directory is recreated.
+	assert.NoError(t, os.Mkdir(dir, 0777))
+	assert.NoError(t, checkFile(w))
+	_, err = io.WriteString(w, "bar\n")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Len(t, events, 1)
+	data, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar\n", string(data))
+}
+
+func Test_fileMoved(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.log")
+	f, err := os.Create(filePath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+	reason, err := fileMoved(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+
+	assert.NoError(t, os.Remove(filePath))
+	reason, err = fileMoved(f)
+	assert.NoError(t, err)
+	assert.Equal(t, FileRemoved, reason)
+
+	assert.NoError(t, os.WriteFile(filePath, nil, 0666))
+	reason, err = fileMoved(f)
+	assert.NoError(t, err)
+	assert.Equal(t, FileReplaced, reason)
+}